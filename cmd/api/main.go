@@ -1,24 +1,71 @@
 package main
 
 import (
+	"emailn/internal/config"
 	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
 	"emailn/internal/endpoints"
+	"emailn/internal/infrastructure/bounce"
 	"emailn/internal/infrastructure/database"
 	"emailn/internal/infrastructure/mail"
+	"emailn/internal/infrastructure/metrics"
+	"emailn/internal/infrastructure/ratelimit"
+	"emailn/internal/logging"
+	"emailn/internal/tracing"
 
+	"context"
+	"errors"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
 )
 
 func main() {
 
-	err := godotenv.Load(".env")
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal(err)
+	}
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if cfg.Database.DSN == memoryDSN {
+			fatal(logger, "migrating database", errors.New("the in-memory storage has no schema to migrate"))
+		}
+		db := database.NewDb(cfg.Database.DSN, logger)
+		if err := runMigrate(context.Background(), db, logger, os.Args[2:]); err != nil {
+			fatal(logger, "migrating database", err)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal(logger, "setting up tracing", err)
 	}
 
 	// GET
@@ -26,25 +73,249 @@ func main() {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware(logger))
 	r.Use(middleware.Recoverer)
+	r.Use(metrics.Middleware)
 
-	db := database.NewDb()
+	store, err := newStorage(context.Background(), cfg.Database, logger)
+	if err != nil {
+		fatal(logger, "opening storage", err)
+	}
+	mailer, err := newMailer(cfg.Mail, logger)
+	if err != nil {
+		fatal(logger, "creating mailer", err)
+	}
 	campaignService := campaign.ServiceImp{
-		Repository: &database.CampaignRepository{Db: db},
-		SendMail:   mail.SendMail,
+		Repository:  store.campaigns,
+		Mailer:      mailer,
+		Throttle:    newThrottle(cfg.RateLimit, cfg.Mail.From),
+		Metrics:     metrics.Messages{Transport: cfg.Mail.Transport},
+		Logger:      logger,
+		Progress:    campaign.NewProgress(),
+		SendTimeout: cfg.Mail.SendTimeout,
+	}
+	webhookService := webhook.ServiceImp{
+		Repository: store.webhooks,
+		Client:     webhook.NewClient(cfg.Webhooks.Timeout),
+		Logger:     logger,
+	}
+	var queue *job.Queue
+	if cfg.Jobs.Workers > 0 {
+		queue = &job.Queue{
+			Repository:   store.jobs,
+			Workers:      cfg.Jobs.Workers,
+			PollInterval: cfg.Jobs.PollInterval,
+			Visibility:   cfg.Jobs.Visibility,
+			MaxAttempts:  cfg.Jobs.MaxAttempts,
+			Backoff:      cfg.Jobs.Backoff,
+			Logger:       logger,
+		}
+		queue.Handle(campaign.SendJob, campaignService.HandleSendJob)
+		queue.Handle(webhook.EventJob, webhookService.HandleEvent)
+		queue.Handle(webhook.DeliverJob, webhookService.HandleDeliver)
+		webhookService.Jobs = queue
+		campaignService.Jobs = queue
+		campaignService.Outbox = store.outbox
+		campaignService.Transactions = store.transactions
+	} else if subscriptions, err := store.webhooks.GetSubscriptions(context.Background(), ""); err == nil && len(subscriptions) > 0 {
+		// without the queue no event is written, the webhooks would be silent
+		logger.Warn("webhooks are not sent without the job queue, set jobs.workers above 0",
+			"webhooks", len(subscriptions))
+	}
+	prometheus.MustRegister(&metrics.CampaignCollector{Counter: store.campaigns})
+	idempotencyService := &idempotency.ServiceImp{
+		Repository:        store.idempotency,
+		Window:            cfg.Idempotency.Window,
+		InProgressTimeout: cfg.Idempotency.InProgressTimeout,
+	}
+	// the envelope sender the campaigns go out with, their bounces come back to it
+	bounceAddress := cfg.Mail.BounceAddress
+	if bounceAddress == "" {
+		bounceAddress = cfg.Mail.From
 	}
 	handler := endpoints.Handler{
-		CampaignService: &campaignService,
+		CampaignService:    &campaignService,
+		IdempotencyService: idempotencyService,
+		WebhookService:     &webhookService,
+		BounceAddress:      bounceAddress,
+		HealthChecks:       newHealthChecks(cfg, store.db, mailer),
+		HealthTimeout:      cfg.Health.Timeout,
 	}
 
+	// probes stay outside the authentication, orchestrators call them anonymously
+	r.Get("/healthz", endpoints.HandlerError(handler.Healthz))
+	r.Get("/readyz", endpoints.HandlerError(handler.Readyz))
+	r.Handle("/metrics", promhttp.Handler())
+
 	r.Route("/campaigns", func(r chi.Router) {
-		r.Use(endpoints.Auth)
+		r.Use(endpoints.Auth(cfg.Auth.ProviderURL, cfg.Auth.ClientID))
+		r.Use(endpoints.IfMatch)
 		r.Post("/", endpoints.HandlerError(handler.CampaignPost))
 		r.Get("/{id}", endpoints.HandlerError(handler.CampaignGetById))
+		r.Get("/{id}/stats", endpoints.HandlerError(handler.CampaignGetStats))
+		r.Get("/{id}/events", endpoints.HandlerError(handler.CampaignEvents))
 		r.Delete("/delete/{id}", endpoints.HandlerError(handler.CampaignDelete))
+		r.Post("/{id}/restore", endpoints.HandlerError(handler.CampaignRestore))
 		r.Patch("/start/{id}", endpoints.HandlerError(handler.CampaignStart))
+		r.Patch("/resume/{id}", endpoints.HandlerError(handler.CampaignResume))
+	})
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(endpoints.Auth(cfg.Auth.ProviderURL, cfg.Auth.ClientID))
+		r.Post("/", endpoints.HandlerError(handler.WebhookPost))
+		r.Get("/", endpoints.HandlerError(handler.WebhookGet))
+		r.Get("/{id}", endpoints.HandlerError(handler.WebhookGetById))
+		r.Put("/{id}", endpoints.HandlerError(handler.WebhookPut))
+		r.Delete("/{id}", endpoints.HandlerError(handler.WebhookDelete))
+		r.Get("/{id}/deliveries", endpoints.HandlerError(handler.WebhookDeliveries))
+		r.Post("/{id}/deliveries/{deliveryId}/replay", endpoints.HandlerError(handler.WebhookReplay))
 	})
 
-	http.ListenAndServe(":3000", r)
+	r.With(endpoints.WebhookAuth(cfg.Bounce.WebhookToken)).Post("/bounces", endpoints.HandlerError(handler.BouncePost))
+
+	// Only the job queue resumes the campaigns left Started, without it they
+	// are logged to be resumed through the API.
+	go func() {
+		if err := campaignService.ResumeStarted(context.Background()); err != nil {
+			logger.Error("resuming started campaigns", "error", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Bounce.Maildir != "" {
+		maildir := bounce.Maildir{Dir: cfg.Bounce.Maildir, Service: &campaignService, BounceAddress: bounceAddress, Logger: logger}
+		go maildir.Run(ctx, cfg.Bounce.PollInterval)
+	}
+
+	queueDone := make(chan struct{})
+	if queue != nil {
+		relay := &outbox.Relay{
+			Repository:   store.outbox,
+			Transactions: store.transactions,
+			Publisher:    queue,
+			PollInterval: cfg.Jobs.PollInterval,
+			MaxAttempts:  cfg.Jobs.MaxAttempts,
+			Backoff:      cfg.Jobs.Backoff,
+			Logger:       logger,
+		}
+		go relay.Run(ctx)
+		go func() {
+			defer close(queueDone)
+			queue.Run(ctx)
+		}()
+	} else {
+		close(queueDone)
+	}
+
+	if cfg.Purge.Retention > 0 {
+		go runPurge(ctx, &campaignService, cfg.Purge, logger)
+	}
+	go runIdempotencyPurge(ctx, idempotencyService, cfg.Idempotency.PurgeInterval, logger)
+
+	server := &http.Server{Addr: cfg.HTTP.Addr, Handler: r}
+	// the event streams never finish on their own, ended they let the drain
+	// complete and their clients reconnect elsewhere
+	server.RegisterOnShutdown(campaignService.Progress.Close)
+	go func() {
+		logger.Info("listening", "addr", cfg.HTTP.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "serving http", err)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("shutting down, draining requests", "timeout", cfg.HTTP.DrainTimeout.String())
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.DrainTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		logger.Warn("drain timeout reached, stopping campaign sends", "error", err)
+	}
+
+	// the sends still running stop after the message in flight and keep their
+	// progress, the next start resumes them
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelStop()
+	if err := campaignService.Shutdown(stopCtx); err != nil {
+		logger.Error("campaign sends did not stop in time", "error", err)
+	}
+	// the jobs of the stopped sends are released for the next start, or for
+	// another replica, to run
+	select {
+	case <-queueDone:
+	case <-stopCtx.Done():
+		logger.Error("job workers did not stop in time")
+	}
+	if closer, ok := mailer.(io.Closer); ok {
+		closer.Close()
+	}
+	if err := shutdownTracing(stopCtx); err != nil {
+		logger.Error("flushing traces", "error", err)
+	}
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func newMailer(cfg config.Mail, logger *slog.Logger) (campaign.Mailer, error) {
+	providerConfig := mail.ProviderConfig{
+		BaseURL:   cfg.API.URL,
+		APIKey:    cfg.API.Key,
+		Domain:    cfg.API.Domain,
+		From:      cfg.From,
+		BatchSize: cfg.API.BatchSize,
+	}
+
+	switch cfg.Transport {
+	case "maildir":
+		return mail.NewMaildirMailer(cfg.Maildir, cfg.From)
+	case "memory":
+		return &mail.Recorder{}, nil
+	case "sendgrid":
+		return mail.NewSendGridMailer(providerConfig)
+	case "mailgun":
+		return mail.NewMailgunMailer(providerConfig)
+	default:
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:          cfg.SMTP.Host,
+			Port:          cfg.SMTP.Port,
+			Username:      cfg.SMTP.Username,
+			Password:      cfg.SMTP.Password,
+			From:          cfg.From,
+			BounceAddress: cfg.BounceAddress,
+			TLSMode:       cfg.SMTP.TLSMode,
+			SkipVerify:    cfg.SMTP.SkipVerify,
+			Logger:        logger,
+		})
+	}
+}
+
+func newThrottle(cfg config.RateLimit, from string) *ratelimit.Limiter {
+	return ratelimit.NewLimiter(ratelimit.Config{
+		PerSecond:                cfg.PerSecond,
+		SenderDomainPerSecond:    cfg.SenderDomainPerSecond,
+		RecipientDomains:         cfg.RecipientDomains,
+		RecipientDomainPerSecond: cfg.RecipientDomainPerSecond,
+		Burst:                    cfg.Burst,
+	}, from)
+}
+
+func newHealthChecks(cfg *config.Config, db *gorm.DB, mailer campaign.Mailer) []endpoints.HealthCheck {
+	checks := []endpoints.HealthCheck{
+		{Name: "oidc", Critical: true, Check: endpoints.OIDCHealthCheck(cfg.Auth.ProviderURL)},
+	}
+	if db != nil {
+		checks = append(checks, endpoints.HealthCheck{Name: "database", Critical: true, Check: database.Ping(db)})
+	}
+	if smtpMailer, ok := mailer.(*mail.SMTPMailer); ok && cfg.Health.CheckSMTP {
+		checks = append(checks, endpoints.HealthCheck{
+			Name:  "smtp",
+			Check: endpoints.CachedCheck(smtpMailer.Ping, cfg.Health.SMTPCacheTTL),
+		})
+	}
+	return checks
 }
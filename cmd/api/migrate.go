@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"emailn/internal/infrastructure/database"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// runMigrate implements `api migrate [up | down [steps] | status]`.
+func runMigrate(ctx context.Context, db *gorm.DB, logger *slog.Logger, args []string) error {
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			logger.Info("migration applied", "version", migration.Version, "name", migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			logger.Info("database schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			logger.Info("migration reverted", "version", migration.Version, "name", migration.Name)
+		}
+		return err
+	case "status":
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			logger.Info("migration pending", "version", migration.Version, "name", migration.Name)
+		}
+		logger.Info("migration status", "available", len(migrator.Migrations), "pending", len(pending))
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, use up, down [steps] or status", command)
+	}
+}
+
+// checkSchema refuses to serve on a database missing migrations, the code
+// would fail on the first query touching them.
+func checkSchema(ctx context.Context, db *gorm.DB) error {
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind, %d migrations pending starting at %d_%s; run `api migrate up`",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
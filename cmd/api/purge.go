@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"emailn/internal/config"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"log/slog"
+	"time"
+)
+
+// runPurge removes the campaigns deleted longer than the retention ago, every
+// interval until ctx is done.
+func runPurge(ctx context.Context, service *campaign.ServiceImp, cfg config.Purge, logger *slog.Logger) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := service.Purge(ctx, cfg.Retention); err != nil {
+			logger.Error("purging deleted campaigns", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runIdempotencyPurge deletes the Idempotency-Keys past their window, every
+// interval until ctx is done.
+func runIdempotencyPurge(ctx context.Context, service *idempotency.ServiceImp, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := service.Purge(ctx); err != nil {
+			logger.Error("purging idempotency keys", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
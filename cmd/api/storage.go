@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"emailn/internal/config"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
+	"emailn/internal/infrastructure/database"
+	"emailn/internal/infrastructure/memory"
+	"emailn/internal/infrastructure/metrics"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// memoryDSN selects the in-memory repositories instead of a database.
+const memoryDSN = "memory"
+
+type campaignRepository interface {
+	campaign.Repository
+	metrics.CampaignCounter
+}
+
+// storage holds the repositories of the configured backend. db is nil for
+// the in-memory one.
+type storage struct {
+	campaigns   campaignRepository
+	idempotency idempotency.Repository
+	jobs        job.Repository
+	outbox      outbox.Repository
+	webhooks    webhook.Repository
+	// transactions spans the repositories above
+	transactions outbox.UnitOfWork
+	db           *gorm.DB
+}
+
+func newStorage(ctx context.Context, cfg config.Database, logger *slog.Logger) (*storage, error) {
+	if cfg.DSN == memoryDSN {
+		logger.Warn("using the in-memory storage, data is lost on restart")
+		return &storage{
+			campaigns:    memory.NewCampaignRepository(),
+			idempotency:  memory.NewIdempotencyRepository(),
+			jobs:         memory.NewJobRepository(),
+			outbox:       memory.NewOutboxRepository(),
+			webhooks:     memory.NewWebhookRepository(),
+			transactions: memory.UnitOfWork{},
+		}, nil
+	}
+
+	db := database.NewDb(cfg.DSN, logger)
+	if err := checkSchema(ctx, db); err != nil {
+		return nil, err
+	}
+	return &storage{
+		campaigns:    &database.CampaignRepository{Db: db, Timeout: cfg.QueryTimeout},
+		idempotency:  &database.IdempotencyRepository{Db: db, Timeout: cfg.QueryTimeout},
+		jobs:         &database.JobRepository{Db: db, Timeout: cfg.QueryTimeout},
+		outbox:       &database.OutboxRepository{Db: db, Timeout: cfg.QueryTimeout},
+		webhooks:     &database.WebhookRepository{Db: db, Timeout: cfg.QueryTimeout},
+		transactions: &database.UnitOfWork{Db: db},
+		db:           db,
+	}, nil
+}
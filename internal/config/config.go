@@ -0,0 +1,341 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	HTTP        HTTP        `yaml:"http"`
+	Database    Database    `yaml:"database"`
+	Auth        Auth        `yaml:"auth"`
+	Mail        Mail        `yaml:"mail"`
+	Bounce      Bounce      `yaml:"bounce"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Purge       Purge       `yaml:"purge"`
+	Jobs        Jobs        `yaml:"jobs"`
+	Webhooks    Webhooks    `yaml:"webhooks"`
+	Health      Health      `yaml:"health"`
+	Log         Log         `yaml:"log"`
+	Tracing     Tracing     `yaml:"tracing"`
+}
+
+type HTTP struct {
+	Addr string `yaml:"addr"`
+	// DrainTimeout is how long in-flight requests, campaign sends included,
+	// get to finish on shutdown before the sends are stopped.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+}
+
+type Database struct {
+	DSN string `yaml:"dsn"`
+	// QueryTimeout bounds every repository operation, 0 leaves it to the
+	// request.
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+}
+
+type Auth struct {
+	ProviderURL string `yaml:"provider_url"`
+	ClientID    string `yaml:"client_id"`
+}
+
+type Mail struct {
+	// Transport is smtp, sendgrid, mailgun, maildir or memory.
+	Transport     string  `yaml:"transport"`
+	From          string  `yaml:"from"`
+	BounceAddress string  `yaml:"bounce_address"`
+	Maildir       string  `yaml:"maildir"`
+	SMTP          SMTP    `yaml:"smtp"`
+	API           MailAPI `yaml:"api"`
+	// SendTimeout bounds the hand off of a message, or a batch, to the
+	// transport, 0 leaves it to the request.
+	SendTimeout time.Duration `yaml:"send_timeout"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// TLSMode is empty to upgrade with STARTTLS when the relay offers it,
+	// starttls to refuse a relay that does not, or tls.
+	TLSMode    string `yaml:"tls_mode"`
+	SkipVerify bool   `yaml:"skip_verify"`
+}
+
+type MailAPI struct {
+	URL       string `yaml:"url"`
+	Key       string `yaml:"key"`
+	Domain    string `yaml:"domain"`
+	BatchSize int    `yaml:"batch_size"`
+}
+
+type Bounce struct {
+	Maildir      string        `yaml:"maildir"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	WebhookToken string        `yaml:"webhook_token"`
+}
+
+// RateLimit holds the sending rates in messages per second, 0 is unlimited.
+// They hold per process: N replicas sending at once send up to N times them.
+type RateLimit struct {
+	PerSecond                float64            `yaml:"per_second"`
+	SenderDomainPerSecond    float64            `yaml:"sender_domain_per_second"`
+	RecipientDomainPerSecond float64            `yaml:"recipient_domain_per_second"`
+	RecipientDomains         map[string]float64 `yaml:"recipient_domains"`
+	Burst                    int                `yaml:"burst"`
+}
+
+type Idempotency struct {
+	// Window is how long an Idempotency-Key is remembered.
+	Window time.Duration `yaml:"window"`
+	// InProgressTimeout is how long a request holds its key before a retry
+	// can take it over, after a crash it would be held for the whole Window.
+	InProgressTimeout time.Duration `yaml:"in_progress_timeout"`
+	// PurgeInterval is how often the keys older than the Window are deleted.
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
+
+type Purge struct {
+	// Retention is how long a deleted campaign can be restored before it is
+	// removed for good, 0 keeps deleted campaigns forever.
+	Retention time.Duration `yaml:"retention"`
+	Interval  time.Duration `yaml:"interval"`
+}
+
+// Jobs configures the durable queue the campaign sends run on.
+type Jobs struct {
+	// Workers is how many jobs this process runs at once, 0 runs the sends
+	// in the request that starts them, without the queue. The sends
+	// interrupted then are not resumed at startup but through the API.
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Visibility is how long a claimed job stays hidden from other workers
+	// without a heartbeat before it is run again.
+	Visibility time.Duration `yaml:"visibility_timeout"`
+	// MaxAttempts and Backoff apply to the outbox messages relayed to the
+	// queue too.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the wait before the first retry, doubled on every attempt.
+	Backoff time.Duration `yaml:"backoff"`
+}
+
+// Webhooks configures the posting of the webhook deliveries, which run on
+// the job queue and are retried with its backoff.
+type Webhooks struct {
+	// Timeout bounds every post to a webhook endpoint.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type Log struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout or otlp.
+	Exporter    string `yaml:"exporter"`
+	ServiceName string `yaml:"service_name"`
+	// Endpoint is the host:port of the OTLP/HTTP collector.
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type Health struct {
+	// Timeout bounds how long /readyz waits for the dependency checks.
+	Timeout time.Duration `yaml:"timeout"`
+	// CheckSMTP adds the SMTP relay to /readyz, reported but not critical.
+	CheckSMTP bool `yaml:"check_smtp"`
+	// SMTPCacheTTL is how long an SMTP check result is reused.
+	SMTPCacheTTL time.Duration `yaml:"smtp_cache_ttl"`
+}
+
+func defaults() Config {
+	return Config{
+		HTTP:        HTTP{Addr: ":3000", DrainTimeout: 30 * time.Second},
+		Auth:        Auth{ClientID: "emailn"},
+		Database:    Database{QueryTimeout: 5 * time.Second},
+		Mail:        Mail{Transport: "smtp", SendTimeout: 30 * time.Second, SMTP: SMTP{Port: 587}},
+		Bounce:      Bounce{PollInterval: time.Minute},
+		Idempotency: Idempotency{Window: 24 * time.Hour, InProgressTimeout: 5 * time.Minute, PurgeInterval: time.Hour},
+		Purge:       Purge{Retention: 30 * 24 * time.Hour, Interval: time.Hour},
+		Jobs:        Jobs{Workers: 2, PollInterval: time.Second, Visibility: time.Minute, MaxAttempts: 5, Backoff: 30 * time.Second},
+		Webhooks:    Webhooks{Timeout: 10 * time.Second},
+		Health:      Health{Timeout: 2 * time.Second, SMTPCacheTTL: 30 * time.Second},
+		Log:         Log{Level: "info", Format: "json"},
+		Tracing:     Tracing{Exporter: "none", ServiceName: "emailn", Endpoint: "localhost:4318", Insecure: true, SampleRatio: 1},
+	}
+}
+
+// Load builds the configuration from the defaults, the optional YAML file
+// named by CONFIG_FILE and the environment, in increasing precedence. A .env
+// file in the working directory, when present, is loaded into the
+// environment first. Every missing or invalid setting is reported in the
+// returned error, not only the first one.
+func Load() (*Config, error) {
+	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	config := defaults()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("loading config file %s: %w", path, err)
+		}
+	}
+
+	env := &envReader{}
+	env.loadInto(&config)
+	problems := append(env.problems, config.validate()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	required := func(value string, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+	notNegative := func(value float64, name string) {
+		if value < 0 {
+			problems = append(problems, name+" must not be negative")
+		}
+	}
+
+	required(c.HTTP.Addr, "http.addr (HTTP_ADDR)")
+	if c.HTTP.DrainTimeout <= 0 {
+		problems = append(problems, "http.drain_timeout (HTTP_DRAIN_TIMEOUT) must be positive")
+	}
+	required(c.Database.DSN, "database.dsn (DATABASE)")
+	if c.Database.QueryTimeout < 0 {
+		problems = append(problems, "database.query_timeout (DATABASE_QUERY_TIMEOUT) must not be negative")
+	}
+	if c.Mail.SendTimeout < 0 {
+		problems = append(problems, "mail.send_timeout (MAIL_SEND_TIMEOUT) must not be negative")
+	}
+	required(c.Auth.ProviderURL, "auth.provider_url (KEYCLOAK)")
+	required(c.Auth.ClientID, "auth.client_id (KEYCLOAK_CLIENT_ID)")
+
+	switch c.Mail.Transport {
+	case "smtp":
+		required(c.Mail.SMTP.Host, "mail.smtp.host (EMAIL_SMTP)")
+		required(c.Mail.From, "mail.from (EMAIL_FROM or EMAIL_USER)")
+		if c.Mail.SMTP.Port < 1 || c.Mail.SMTP.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("mail.smtp.port (EMAIL_PORT) must be between 1 and 65535, got %d", c.Mail.SMTP.Port))
+		}
+		if c.Mail.SMTP.TLSMode != "" && c.Mail.SMTP.TLSMode != "starttls" && c.Mail.SMTP.TLSMode != "tls" {
+			problems = append(problems, fmt.Sprintf("mail.smtp.tls_mode (EMAIL_TLS) must be empty, starttls or tls, got %q", c.Mail.SMTP.TLSMode))
+		}
+	case "sendgrid", "mailgun":
+		required(c.Mail.From, "mail.from (EMAIL_FROM or EMAIL_USER)")
+		required(c.Mail.API.URL, "mail.api.url (MAIL_API_URL)")
+		required(c.Mail.API.Key, "mail.api.key (MAIL_API_KEY)")
+		if c.Mail.Transport == "mailgun" {
+			required(c.Mail.API.Domain, "mail.api.domain (MAIL_API_DOMAIN)")
+		}
+	case "maildir":
+		required(c.Mail.Maildir, "mail.maildir (MAIL_MAILDIR)")
+	case "memory":
+	default:
+		problems = append(problems, fmt.Sprintf("mail.transport (MAIL_TRANSPORT) must be smtp, sendgrid, mailgun, maildir or memory, got %q", c.Mail.Transport))
+	}
+
+	if c.Bounce.Maildir != "" && c.Bounce.PollInterval <= 0 {
+		problems = append(problems, "bounce.poll_interval (BOUNCE_POLL_INTERVAL) must be positive")
+	}
+
+	if c.Idempotency.Window <= 0 {
+		problems = append(problems, "idempotency.window (IDEMPOTENCY_WINDOW) must be positive")
+	}
+	if c.Idempotency.InProgressTimeout <= 0 {
+		problems = append(problems, "idempotency.in_progress_timeout (IDEMPOTENCY_IN_PROGRESS_TIMEOUT) must be positive")
+	}
+	if c.Idempotency.PurgeInterval <= 0 {
+		problems = append(problems, "idempotency.purge_interval (IDEMPOTENCY_PURGE_INTERVAL) must be positive")
+	}
+
+	if c.Purge.Retention < 0 {
+		problems = append(problems, "purge.retention (PURGE_RETENTION) must not be negative")
+	}
+	if c.Purge.Retention > 0 && c.Purge.Interval <= 0 {
+		problems = append(problems, "purge.interval (PURGE_INTERVAL) must be positive")
+	}
+
+	if c.Jobs.Workers < 0 {
+		problems = append(problems, "jobs.workers (JOBS_WORKERS) must not be negative")
+	}
+	if c.Jobs.Workers > 0 {
+		if c.Jobs.PollInterval <= 0 {
+			problems = append(problems, "jobs.poll_interval (JOBS_POLL_INTERVAL) must be positive")
+		}
+		if c.Jobs.Visibility <= 0 {
+			problems = append(problems, "jobs.visibility_timeout (JOBS_VISIBILITY_TIMEOUT) must be positive")
+		}
+		if c.Jobs.MaxAttempts < 1 {
+			problems = append(problems, "jobs.max_attempts (JOBS_MAX_ATTEMPTS) must be at least 1")
+		}
+		if c.Jobs.Backoff < 0 {
+			problems = append(problems, "jobs.backoff (JOBS_BACKOFF) must not be negative")
+		}
+	}
+	if c.Webhooks.Timeout <= 0 {
+		problems = append(problems, "webhooks.timeout (WEBHOOKS_TIMEOUT) must be positive")
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		problems = append(problems, fmt.Sprintf("log.format (LOG_FORMAT) must be json or text, got %q", c.Log.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		required(c.Tracing.Endpoint, "tracing.endpoint (TRACING_ENDPOINT)")
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter (TRACING_EXPORTER) must be none, stdout or otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+	}
+
+	if c.Health.Timeout <= 0 {
+		problems = append(problems, "health.timeout (HEALTH_TIMEOUT) must be positive")
+	}
+	if c.Health.CheckSMTP && c.Health.SMTPCacheTTL < 0 {
+		problems = append(problems, "health.smtp_cache_ttl (HEALTH_SMTP_CACHE_TTL) must not be negative")
+	}
+
+	notNegative(c.RateLimit.PerSecond, "rate_limit.per_second (RATE_LIMIT_PER_SECOND)")
+	notNegative(c.RateLimit.SenderDomainPerSecond, "rate_limit.sender_domain_per_second (RATE_LIMIT_SENDER_DOMAIN_PER_SECOND)")
+	notNegative(c.RateLimit.RecipientDomainPerSecond, "rate_limit.recipient_domain_per_second (RATE_LIMIT_RECIPIENT_DOMAIN_PER_SECOND)")
+	notNegative(float64(c.RateLimit.Burst), "rate_limit.burst (RATE_LIMIT_BURST)")
+	for domain, perSecond := range c.RateLimit.RecipientDomains {
+		notNegative(perSecond, "rate_limit.recipient_domains."+domain)
+	}
+	return problems
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DATABASE", "host=localhost user=anamarina password=123456 dbname=emailn")
+	t.Setenv("KEYCLOAK", "http://localhost:8080/realms/provider")
+	t.Setenv("EMAIL_SMTP", "smtp.test.com")
+	t.Setenv("EMAIL_USER", "campaigns@emailn.com")
+}
+
+func Test_Load_should_read_environment_with_defaults(t *testing.T) {
+	assert := assert.New(t)
+	setRequiredEnv(t)
+	t.Setenv("RATE_LIMIT_RECIPIENT_DOMAINS", "gmail.com=10,Outlook.com=2.5")
+
+	cfg, err := Load()
+
+	assert.Nil(err)
+	assert.Equal(":3000", cfg.HTTP.Addr)
+	assert.Equal("emailn", cfg.Auth.ClientID)
+	assert.Equal(587, cfg.Mail.SMTP.Port)
+	assert.Equal("campaigns@emailn.com", cfg.Mail.From)
+	assert.Equal(time.Minute, cfg.Bounce.PollInterval)
+	assert.Equal(720*time.Hour, cfg.Purge.Retention)
+	assert.Equal(map[string]float64{"gmail.com": 10, "outlook.com": 2.5}, cfg.RateLimit.RecipientDomains)
+}
+
+func Test_Load_should_let_environment_override_yaml_file(t *testing.T) {
+	assert := assert.New(t)
+	setRequiredEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+http:
+  addr: ":8000"
+mail:
+  smtp:
+    port: 465
+    tls_mode: tls
+bounce:
+  poll_interval: 30s
+`), 0o600)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("EMAIL_PORT", "2525")
+
+	cfg, err := Load()
+
+	assert.Nil(err)
+	assert.Equal(":8000", cfg.HTTP.Addr)
+	assert.Equal(2525, cfg.Mail.SMTP.Port)
+	assert.Equal("tls", cfg.Mail.SMTP.TLSMode)
+	assert.Equal(30*time.Second, cfg.Bounce.PollInterval)
+}
+
+func Test_Load_should_report_every_invalid_setting(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("EMAIL_PORT", "abc")
+	t.Setenv("RATE_LIMIT_PER_SECOND", "-1")
+	t.Setenv("MAIL_TRANSPORT", "smtp")
+	t.Setenv("LOG_FORMAT", "xml")
+
+	_, err := Load()
+
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "EMAIL_PORT must be an integer")
+	assert.Contains(err.Error(), "database.dsn (DATABASE) is required")
+	assert.Contains(err.Error(), "auth.provider_url (KEYCLOAK) is required")
+	assert.Contains(err.Error(), "mail.smtp.host (EMAIL_SMTP) is required")
+	assert.Contains(err.Error(), "rate_limit.per_second (RATE_LIMIT_PER_SECOND) must not be negative")
+	assert.Contains(err.Error(), `log.format (LOG_FORMAT) must be json or text, got "xml"`)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envReader overrides the settings with the environment variables that are
+// set, collecting every value it cannot parse.
+type envReader struct {
+	problems []string
+}
+
+func (e *envReader) loadInto(c *Config) {
+	e.string("HTTP_ADDR", &c.HTTP.Addr)
+	e.duration("HTTP_DRAIN_TIMEOUT", &c.HTTP.DrainTimeout)
+	e.string("DATABASE", &c.Database.DSN)
+	e.duration("DATABASE_QUERY_TIMEOUT", &c.Database.QueryTimeout)
+	e.string("KEYCLOAK", &c.Auth.ProviderURL)
+	e.string("KEYCLOAK_CLIENT_ID", &c.Auth.ClientID)
+
+	e.string("MAIL_TRANSPORT", &c.Mail.Transport)
+	e.duration("MAIL_SEND_TIMEOUT", &c.Mail.SendTimeout)
+	e.string("EMAIL_SMTP", &c.Mail.SMTP.Host)
+	e.int("EMAIL_PORT", &c.Mail.SMTP.Port)
+	e.string("EMAIL_USER", &c.Mail.SMTP.Username)
+	e.string("EMAIL_PASSWORD", &c.Mail.SMTP.Password)
+	e.string("EMAIL_TLS", &c.Mail.SMTP.TLSMode)
+	e.bool("EMAIL_TLS_SKIP_VERIFY", &c.Mail.SMTP.SkipVerify)
+	e.string("EMAIL_FROM", &c.Mail.From)
+	if c.Mail.From == "" {
+		// the relay account has always been the sender
+		c.Mail.From = c.Mail.SMTP.Username
+	}
+	e.string("EMAIL_BOUNCE_ADDRESS", &c.Mail.BounceAddress)
+	e.string("MAIL_MAILDIR", &c.Mail.Maildir)
+	e.string("MAIL_API_URL", &c.Mail.API.URL)
+	e.string("MAIL_API_KEY", &c.Mail.API.Key)
+	e.string("MAIL_API_DOMAIN", &c.Mail.API.Domain)
+	e.int("MAIL_API_BATCH_SIZE", &c.Mail.API.BatchSize)
+
+	e.string("BOUNCE_MAILDIR", &c.Bounce.Maildir)
+	e.duration("BOUNCE_POLL_INTERVAL", &c.Bounce.PollInterval)
+	e.string("BOUNCE_WEBHOOK_TOKEN", &c.Bounce.WebhookToken)
+
+	e.duration("IDEMPOTENCY_WINDOW", &c.Idempotency.Window)
+	e.duration("IDEMPOTENCY_IN_PROGRESS_TIMEOUT", &c.Idempotency.InProgressTimeout)
+	e.duration("IDEMPOTENCY_PURGE_INTERVAL", &c.Idempotency.PurgeInterval)
+
+	e.duration("PURGE_RETENTION", &c.Purge.Retention)
+	e.duration("PURGE_INTERVAL", &c.Purge.Interval)
+
+	e.int("JOBS_WORKERS", &c.Jobs.Workers)
+	e.duration("JOBS_POLL_INTERVAL", &c.Jobs.PollInterval)
+	e.duration("JOBS_VISIBILITY_TIMEOUT", &c.Jobs.Visibility)
+	e.int("JOBS_MAX_ATTEMPTS", &c.Jobs.MaxAttempts)
+	e.duration("JOBS_BACKOFF", &c.Jobs.Backoff)
+	e.duration("WEBHOOKS_TIMEOUT", &c.Webhooks.Timeout)
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+	e.string("LOG_FORMAT", &c.Log.Format)
+
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.string("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.string("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	e.bool("TRACING_INSECURE", &c.Tracing.Insecure)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.duration("HEALTH_TIMEOUT", &c.Health.Timeout)
+	e.bool("HEALTH_CHECK_SMTP", &c.Health.CheckSMTP)
+	e.duration("HEALTH_SMTP_CACHE_TTL", &c.Health.SMTPCacheTTL)
+
+	e.float("RATE_LIMIT_PER_SECOND", &c.RateLimit.PerSecond)
+	e.float("RATE_LIMIT_SENDER_DOMAIN_PER_SECOND", &c.RateLimit.SenderDomainPerSecond)
+	e.float("RATE_LIMIT_RECIPIENT_DOMAIN_PER_SECOND", &c.RateLimit.RecipientDomainPerSecond)
+	e.domainRates("RATE_LIMIT_RECIPIENT_DOMAINS", &c.RateLimit.RecipientDomains)
+	e.int("RATE_LIMIT_BURST", &c.RateLimit.Burst)
+}
+
+func (e *envReader) invalid(name string, value string, kind string) {
+	e.problems = append(e.problems, fmt.Sprintf("%s must be %s, got %q", name, kind, value))
+}
+
+func (e *envReader) string(name string, target *string) {
+	if value, ok := os.LookupEnv(name); ok {
+		*target = value
+	}
+}
+
+func (e *envReader) int(name string, target *int) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.invalid(name, value, "an integer")
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) float(name string, target *float64) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.invalid(name, value, "a number")
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) bool(name string, target *bool) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid(name, value, "true or false")
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) duration(name string, target *time.Duration) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid(name, value, "a duration like 30s or 5m")
+		return
+	}
+	*target = parsed
+}
+
+// domainRates reads a list like "gmail.com=10,outlook.com=5".
+func (e *envReader) domainRates(name string, target *map[string]float64) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	rates := map[string]float64{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		domain, perSecond, found := strings.Cut(pair, "=")
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(perSecond), 64)
+		if !found || err != nil {
+			e.invalid(name, value, "a list like gmail.com=10,outlook.com=5")
+			return
+		}
+		rates[strings.ToLower(strings.TrimSpace(domain))] = parsed
+	}
+	*target = rates
+}
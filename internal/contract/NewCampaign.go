@@ -6,4 +6,6 @@ type NewCampaign struct {
 	Emails    []string
 	Status    string
 	CreatedBy string
+	// MaxPerSecond optionally limits the sending rate of the campaign.
+	MaxPerSecond float64
 }
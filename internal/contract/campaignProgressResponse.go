@@ -0,0 +1,11 @@
+package contract
+
+// CampaignProgressResponse is the data of an event streamed while the
+// campaign is sent.
+type CampaignProgressResponse struct {
+	ID     string
+	Status string
+	Total  int64
+	Sent   int64
+	Failed int64
+}
@@ -7,4 +7,6 @@ type CampaignResponse struct {
 	Status               string
 	AmountOfEmailsToSend int
 	CreatedBy            string
+	// Version is the ETag of the campaign, see If-Match.
+	Version int `json:"-"`
 }
@@ -0,0 +1,19 @@
+package contract
+
+import "time"
+
+type CampaignStatsResponse struct {
+	ID           string
+	Status       string
+	Total        int64
+	Sent         int64
+	Failed       int64
+	Bounced      int64
+	Suppressed   int64
+	Opened       int64
+	Clicked      int64
+	Unsubscribed int64
+	StartedOn    *time.Time
+	FinishedOn   *time.Time
+	Throughput   float64
+}
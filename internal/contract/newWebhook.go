@@ -0,0 +1,9 @@
+package contract
+
+type NewWebhook struct {
+	URL    string
+	Events []string
+	// Secret signs the deliveries, a random one is made when it is empty.
+	Secret    string
+	CreatedBy string
+}
@@ -0,0 +1,22 @@
+package contract
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookDeliveryResponse struct {
+	ID        string
+	EventID   string
+	EventType string
+	Payload   json.RawMessage
+	Status    string
+	Attempts  int
+	// ResponseStatus is the HTTP status of the last attempt, 0 when the
+	// endpoint did not answer.
+	ResponseStatus int
+	LastError      string
+	CreatedOn      time.Time
+	LastAttemptOn  *time.Time
+	DeliveredOn    *time.Time
+}
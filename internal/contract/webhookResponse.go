@@ -0,0 +1,12 @@
+package contract
+
+import "time"
+
+type WebhookResponse struct {
+	ID     string
+	URL    string
+	Events []string
+	// Secret is only returned when the webhook is created.
+	Secret    string `json:",omitempty"`
+	CreatedOn time.Time
+}
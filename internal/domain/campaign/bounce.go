@@ -0,0 +1,45 @@
+package campaign
+
+import (
+	"strings"
+	"time"
+)
+
+// Bounce is a single recipient entry of a delivery status notification
+// (RFC 3464) already matched to the contact it was sent to.
+type Bounce struct {
+	ContactID  string
+	Recipient  string
+	Action     string
+	Status     string
+	Diagnostic string
+}
+
+// Failed reports whether the notification is a definitive delivery failure.
+// delayed, delivered, relayed and expanded actions are only informative.
+func (b *Bounce) Failed() bool {
+	return strings.EqualFold(b.Action, "failed")
+}
+
+// Hard reports whether the failure is permanent (status class 5).
+func (b *Bounce) Hard() bool {
+	return strings.HasPrefix(b.Status, "5")
+}
+
+type Suppression struct {
+	Email     string `gorm:"size:100;primaryKey"`
+	Reason    string `gorm:"size:255"`
+	CreatedOn time.Time
+}
+
+func NewSuppression(email string, reason string) *Suppression {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	return &Suppression{
+		Email:     strings.ToLower(email),
+		Reason:    reason,
+		CreatedOn: time.Now(),
+	}
+}
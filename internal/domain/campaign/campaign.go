@@ -2,6 +2,8 @@ package campaign
 
 import (
 	internalerrors "emailn/internal/internal-errors"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/rs/xid"
@@ -15,31 +17,169 @@ const (
 	Done     = "Done"
 )
 
+const (
+	ContactPending    = "Pending"
+	ContactSending    = "Sending"
+	ContactSent       = "Sent"
+	ContactFailed     = "Failed"
+	ContactBounced    = "Bounced"
+	ContactSuppressed = "Suppressed"
+)
+
 type Contact struct {
 	ID         string `gorm:"size:50"`
 	Email      string `validate:"email" gorm:"size:100"`
-	CampaignId string `gorm:"size:50"`
+	CampaignId string `gorm:"size:50;index"`
+	Status     string `gorm:"size:20"`
+	// ProviderMessageID is the id the mail transport gave to the message.
+	ProviderMessageID string `gorm:"size:255;index"`
+	// SendRunID is the run of the campaign that mailed the contact.
+	SendRunID      string `gorm:"size:50"`
+	SentOn         *time.Time
+	BouncedOn      *time.Time
+	OpenedOn       *time.Time
+	ClickedOn      *time.Time
+	UnsubscribedOn *time.Time
+	// changed is set by the Mark methods, Update only writes the contacts
+	// changed since they were loaded or last saved.
+	changed bool
 }
 
 type Campaign struct {
-	ID        string    `validate:"required" gorm:"size:50"`
-	Name      string    `validate:"min=5,max=24" gorm:"size:100"`
-	CreatedOn time.Time `validate:"required"`
-	Content   string    `validate:"min=5,max=1024" gorm:"size:1024"`
-	Contacts  []Contact `validate:"min=1,dive"`
-	Status    string    `gorm:"size:20"`
-	CreatedBy string    `validate:"email" gorm:"size:50"`
+	ID         string    `validate:"required" gorm:"size:50"`
+	Name       string    `validate:"min=5,max=24" gorm:"size:100"`
+	CreatedOn  time.Time `validate:"required"`
+	Content    string    `validate:"min=5,max=1024" gorm:"size:1024"`
+	Contacts   []Contact `validate:"min=1,dive"`
+	Status     string    `gorm:"size:20"`
+	CreatedBy  string    `validate:"email" gorm:"size:50"`
+	StartedOn  *time.Time
+	FinishedOn *time.Time
+	// SendRunID identifies the current attempt to send the campaign, a new
+	// one is made every time the campaign is started or resumed.
+	SendRunID string `gorm:"size:50"`
+	// MaxPerSecond limits the sending rate of this campaign below the
+	// configured rates, 0 means no override.
+	MaxPerSecond float64
+	// DeletedOn is set while the campaign is Deleted, the campaign is purged
+	// once it is older than the retention.
+	DeletedOn *time.Time `gorm:"column:deleted_at;index"`
+	// Version is bumped by every update of the campaign, an update of an
+	// older version fails with ErrVersionConflict.
+	Version int `gorm:"not null;default:1"`
+}
+
+// MarkSending records that the message is about to be handed to the Mailer,
+// before it is known whether it went out.
+func (c *Contact) MarkSending(runID string) {
+	c.Status = ContactSending
+	c.SendRunID = runID
+	c.changed = true
+}
+
+// MarkPending puts back a contact whose message was not handed to the Mailer.
+func (c *Contact) MarkPending() {
+	c.Status = ContactPending
+	c.changed = true
+}
+
+func (c *Contact) MarkSent(providerMessageID string) {
+	now := time.Now()
+	c.Status = ContactSent
+	c.ProviderMessageID = providerMessageID
+	c.SentOn = &now
+	c.changed = true
+}
+
+func (c *Contact) MarkFailed() {
+	c.Status = ContactFailed
+	c.changed = true
+}
+
+func (c *Contact) MarkBounced() {
+	now := time.Now()
+	c.Status = ContactBounced
+	c.BouncedOn = &now
+	c.changed = true
+}
+
+// MarkSaved is called by the repositories once the contact is written.
+func (c *Contact) MarkSaved() {
+	c.changed = false
+}
+
+// ChangedContacts are the contacts marked since they were loaded or saved.
+func (c *Campaign) ChangedContacts() []*Contact {
+	var changed []*Contact
+	for i := range c.Contacts {
+		if c.Contacts[i].changed {
+			changed = append(changed, &c.Contacts[i])
+		}
+	}
+	return changed
+}
+
+// Suppress marks the contacts whose address is on the suppression list so
+// they are skipped when the campaign is sent.
+func (c *Campaign) Suppress(emails []string) {
+	suppressed := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		suppressed[strings.ToLower(email)] = true
+	}
+	for i := range c.Contacts {
+		if c.Contacts[i].Status == ContactPending && suppressed[strings.ToLower(c.Contacts[i].Email)] {
+			c.Contacts[i].Status = ContactSuppressed
+			c.Contacts[i].changed = true
+		}
+	}
+}
+
+func (c *Campaign) Start() {
+	now := time.Now()
+	c.Status = Started
+	c.StartedOn = &now
+	c.SendRunID = xid.New().String()
+}
+
+func (c *Campaign) Resume() {
+	c.Status = Started
+	c.SendRunID = xid.New().String()
+}
+
+// BackToPending lets a campaign whose send failed be started again. The
+// contacts already sent keep their status and are not mailed twice.
+func (c *Campaign) BackToPending() {
+	c.Status = Peding
 }
 
 func (c *Campaign) Done() {
+	now := time.Now()
 	c.Status = Done
+	c.FinishedOn = &now
+}
+func (c *Campaign) LimitRate(perSecond float64) error {
+	if perSecond < 0 {
+		return errors.New("maxpersecond must not be negative")
+	}
+	c.MaxPerSecond = perSecond
+	return nil
 }
+
 func (c *Campaign) Cancel() {
 	c.Status = Canceled
 }
 
 func (c *Campaign) Delete() {
+	now := time.Now()
 	c.Status = Deleted
+	c.DeletedOn = &now
+}
+
+// Restore brings a deleted campaign back to Pending, only pending campaigns
+// can be deleted.
+func (c *Campaign) Restore() {
+	c.Status = Peding
+	c.DeletedOn = nil
 }
 
 func NewCampaign(name string, content string, emails []string, createdBy string) (*Campaign, error) {
@@ -48,6 +188,7 @@ func NewCampaign(name string, content string, emails []string, createdBy string)
 	for index, email := range emails {
 		contacts[index].Email = email
 		contacts[index].ID = xid.New().String()
+		contacts[index].Status = ContactPending
 	}
 
 	campaign := &Campaign{
@@ -58,6 +199,7 @@ func NewCampaign(name string, content string, emails []string, createdBy string)
 		Contacts:  contacts,
 		Status:    Peding,
 		CreatedBy: createdBy,
+		Version:   1,
 	}
 	// fmt.Print(campaign)
 	err := internalerrors.ValidateStruct(campaign)
@@ -1,6 +1,8 @@
 package campaign
 
 import (
+	internalerrors "emailn/internal/internal-errors"
+	"errors"
 	"testing"
 	"time"
 
@@ -97,7 +99,21 @@ func Test_NewCampaign_MustValidateContacts(t *testing.T) {
 
 	assert := assert.New(t)
 	_, err := NewCampaign(name, content, []string{"email_invalid"}, createBy)
-	assert.Equal("email is invalid", err.Error())
+	assert.Equal("contacts[0].email is invalid", err.Error())
+
+}
+
+func Test_NewCampaign_MustReportEveryInvalidField(t *testing.T) {
+
+	assert := assert.New(t)
+	_, err := NewCampaign("", content, []string{"teste1@test.com", "teste2@test.com", "teste3@test.com", "invalid"}, "")
+	var validationError *internalerrors.ValidationError
+	assert.True(errors.As(err, &validationError))
+	assert.Equal([]internalerrors.FieldError{
+		{Field: "name", Rule: "min", Param: "5", Message: "name is required with min 5"},
+		{Field: "contacts[3].email", Rule: "email", Message: "contacts[3].email is invalid"},
+		{Field: "createdby", Rule: "email", Message: "createdby is invalid"},
+	}, validationError.Fields)
 
 }
 
@@ -108,3 +124,22 @@ func Test_NewCampaign_MustValidateCreatedBy(t *testing.T) {
 	assert.Equal("createdby is invalid", err.Error())
 
 }
+
+func Test_Campaign_Suppress_MarksOnlyListedContacts(t *testing.T) {
+
+	assert := assert.New(t)
+	campaign, _ := NewCampaign(name, content, contacts, createBy)
+	campaign.Suppress([]string{"EMAIL2@e.com"})
+	assert.Equal(ContactPending, campaign.Contacts[0].Status)
+	assert.Equal(ContactSuppressed, campaign.Contacts[1].Status)
+
+}
+
+func Test_Message_RenderedBody_AppliesSubstitutions(t *testing.T) {
+
+	assert := assert.New(t)
+	campaign, _ := NewCampaign(name, "Hi {{email}}!", contacts, createBy)
+	message := campaign.MessageTo(&campaign.Contacts[0])
+	assert.Equal("Hi email1@e.com!", message.RenderedBody())
+
+}
@@ -0,0 +1,87 @@
+package campaign
+
+import (
+	"context"
+	"emailn/internal/domain/webhook"
+)
+
+type campaignEvent struct {
+	CampaignID string `json:"campaign_id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	CreatedBy  string `json:"created_by"`
+}
+
+type bounceEvent struct {
+	CampaignID string `json:"campaign_id"`
+	ContactID  string `json:"contact_id"`
+	Email      string `json:"email"`
+	Status     string `json:"status"`
+	Hard       bool   `json:"hard"`
+	CreatedBy  string `json:"created_by"`
+}
+
+type suppressionEvent struct {
+	CampaignID string `json:"campaign_id"`
+	ContactID  string `json:"contact_id"`
+	Email      string `json:"email"`
+	Reason     string `json:"reason"`
+	CreatedBy  string `json:"created_by"`
+}
+
+// ownedEvent is the data of an event, only the webhooks of its owner get it.
+type ownedEvent interface {
+	owner() string
+}
+
+func (e campaignEvent) owner() string { return e.CreatedBy }
+
+func (e bounceEvent) owner() string { return e.CreatedBy }
+
+func (e suppressionEvent) owner() string { return e.CreatedBy }
+
+func newCampaignEvent(campaign *Campaign) campaignEvent {
+	return campaignEvent{
+		CampaignID: campaign.ID,
+		Name:       campaign.Name,
+		Status:     campaign.Status,
+		CreatedBy:  campaign.CreatedBy,
+	}
+}
+
+// saveWithEvent runs save and, with an Outbox, writes the webhook event of
+// eventType in the same transaction. Without an Outbox no event is sent.
+func (s *ServiceImp) saveWithEvent(ctx context.Context, eventType string, data ownedEvent, save func(ctx context.Context) error) error {
+	if s.Outbox == nil {
+		return save(ctx)
+	}
+	return s.Transactions.Do(ctx, func(ctx context.Context) error {
+		if err := save(ctx); err != nil {
+			return err
+		}
+		return s.writeEvent(ctx, eventType, data)
+	})
+}
+
+// startWithEvent starts the campaign, see start, and sends CampaignStarted.
+func (s *ServiceImp) startWithEvent(ctx context.Context, campaign *Campaign) error {
+	if s.Outbox == nil {
+		return s.start(ctx, campaign)
+	}
+	return s.Transactions.Do(ctx, func(ctx context.Context) error {
+		if err := s.start(ctx, campaign); err != nil {
+			return err
+		}
+		return s.writeEvent(ctx, webhook.CampaignStarted, newCampaignEvent(campaign))
+	})
+}
+
+// writeEvent writes the webhook event to the Outbox, in the transaction
+// carried by ctx.
+func (s *ServiceImp) writeEvent(ctx context.Context, eventType string, data ownedEvent) error {
+	message, err := webhook.EventMessage(eventType, data.owner(), data)
+	if err != nil {
+		return err
+	}
+	return s.Outbox.Create(ctx, message)
+}
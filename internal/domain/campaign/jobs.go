@@ -0,0 +1,154 @@
+package campaign
+
+import (
+	"context"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
+	internalerrors "emailn/internal/internal-errors"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// SendJob is the kind of the jobs sending a campaign.
+const SendJob = "campaign.send"
+
+var (
+	// ErrSendQueued is returned when the send of the campaign is already on
+	// the job queue, scheduled or running.
+	ErrSendQueued            = errors.New("the campaign send is already queued")
+	ErrSchedulingUnavailable = errors.New("scheduling needs the job queue")
+)
+
+// JobQueue is the durable queue the sends go through, see job.Queue.
+type JobQueue interface {
+	Enqueue(ctx context.Context, job *job.Job) error
+}
+
+type sendPayload struct {
+	CampaignID string `json:"campaign_id"`
+}
+
+// queueSend writes the message queueing the send of the campaign, which must
+// be in status, see Outbox. A Pending campaign is started in the same
+// transaction, a Started one is left as it is for the job to resume.
+func (s *ServiceImp) queueSend(ctx context.Context, id string, status string) error {
+	campaignSaved, err := s.Repository.GetBy(ctx, id)
+	if err != nil {
+		return internalerrors.ProcessErrorToReturn(err)
+	}
+	if err := checkVersion(ctx, campaignSaved); err != nil {
+		return err
+	}
+	if campaignSaved.Status != status {
+		return ErrStatusInvalid
+	}
+
+	message, err := outbox.New(SendJob, SendJob+":"+id, sendPayload{CampaignID: id})
+	if err != nil {
+		return internalerrors.ErrInternal
+	}
+	err = s.Transactions.Do(ctx, func(ctx context.Context) error {
+		if status == Peding {
+			if err := s.start(ctx, campaignSaved); err != nil {
+				return err
+			}
+			if err := s.writeEvent(ctx, webhook.CampaignStarted, newCampaignEvent(campaignSaved)); err != nil {
+				return err
+			}
+		}
+		return s.Outbox.Create(ctx, message)
+	})
+	if err != nil {
+		s.logger(ctx).Error("queueing campaign send", "campaign_id", id, "error", err)
+		return updateError(err)
+	}
+	s.logger(ctx).Info("campaign send queued", "campaign_id", id, "message_id", message.ID)
+	return nil
+}
+
+// enqueueSend puts the send of a Pending campaign on the job queue to run at
+// the given time. There is only one send queued per campaign.
+func (s *ServiceImp) enqueueSend(ctx context.Context, id string, at time.Time) error {
+	campaignSaved, err := s.Repository.GetBy(ctx, id)
+	if err != nil {
+		return internalerrors.ProcessErrorToReturn(err)
+	}
+	if err := checkVersion(ctx, campaignSaved); err != nil {
+		return err
+	}
+	if campaignSaved.Status != Peding {
+		return ErrStatusInvalid
+	}
+
+	sendJob, err := job.New(SendJob, SendJob+":"+id, sendPayload{CampaignID: id}, at)
+	if err != nil {
+		return internalerrors.ErrInternal
+	}
+	err = s.Jobs.Enqueue(ctx, sendJob)
+	if errors.Is(err, job.ErrAlreadyQueued) {
+		return ErrSendQueued
+	}
+	if err != nil {
+		s.logger(ctx).Error("queueing campaign send", "campaign_id", id, "error", err)
+		return updateError(err)
+	}
+	s.logger(ctx).Info("campaign send queued", "campaign_id", id, "job_id", sendJob.ID, "run_at", sendJob.RunAt)
+	return nil
+}
+
+// HandleSendJob runs a SendJob: it starts the campaign when it is Pending or
+// resumes it when a previous attempt left it Started. A failed attempt keeps
+// the campaign Started for the next one, only the last one, or a send refused
+// permanently, puts it back to Pending.
+func (s *ServiceImp) HandleSendJob(ctx context.Context, sendJob *job.Job) (err error) {
+	var payload sendPayload
+	if err := sendJob.Decode(&payload); err != nil {
+		return err
+	}
+	ctx, span := tracer.Start(ctx, "campaign.HandleSendJob", trace.WithAttributes(
+		attribute.String("campaign.id", payload.CampaignID), attribute.Int("job.attempt", sendJob.Attempts)))
+	defer func() { endSpan(span, err) }()
+
+	if !s.beginSending() {
+		return fmt.Errorf("%w: %w", job.ErrReleased, ErrShuttingDown)
+	}
+	defer s.sending.Done()
+
+	campaignSaved, err := s.Repository.GetBy(ctx, payload.CampaignID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger(ctx).Info("campaign gone before its send", "campaign_id", payload.CampaignID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch campaignSaved.Status {
+	case Peding:
+		err = s.startWithEvent(ctx, campaignSaved)
+	case Started:
+		err = s.resume(ctx, campaignSaved)
+	default:
+		s.logger(ctx).Info("campaign has nothing to send", "campaign_id", campaignSaved.ID, "status", campaignSaved.Status)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.deliver(ctx, campaignSaved, !sendJob.LastAttempt())
+	if errors.Is(err, ErrSendInterrupted) {
+		return fmt.Errorf("%w: %w", job.ErrReleased, err)
+	}
+	if errors.Is(err, ErrSendRefused) {
+		// the campaign is back to Pending, another attempt would send it again
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrRecipientRejected is returned by a Mailer when the transport refused a
+// single recipient permanently. The contact is marked failed and the campaign
+// goes on; any other error aborts the send.
+var ErrRecipientRejected = errors.New("recipient rejected")
+
+// ErrPermanent is wrapped by a Mailer when the transport refused the message
+// or the batch for a reason retrying does not fix, like a bad API key or
+// request. The send is not retried.
+var ErrPermanent = errors.New("refused permanently")
+
+type Message struct {
+	CampaignID string
+	ContactID  string
+	To         string
+	Subject    string
+	// Body may reference the Substitutions of the recipient as {{name}}.
+	Body          string
+	Substitutions map[string]string
+	// MaxPerSecond overrides the sending rate for the campaign, 0 keeps the
+	// configured rates.
+	MaxPerSecond float64
+	// ProviderMessageID is filled in by the Mailer with the id the transport
+	// assigned to the message, unique to the recipient even in a batch.
+	ProviderMessageID string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// BatchMailer is implemented by transports that deliver many recipients of
+// the same campaign in one call, substituting the per-recipient values
+// themselves. Every message of a batch shares the Subject and Body.
+type BatchMailer interface {
+	Mailer
+	BatchSize() int
+	SendBatch(ctx context.Context, messages []*Message) error
+}
+
+// Throttle holds a message back until it can be sent without exceeding the
+// sending rates of the relay.
+type Throttle interface {
+	Wait(ctx context.Context, message *Message) error
+}
+
+const (
+	MessageSent    = "sent"
+	MessageFailed  = "failed"
+	MessageRetried = "retried"
+)
+
+// Metrics counts the outcome of the messages: sent, failed for a rejected
+// recipient, or retried when the Mailer refused them and they wait for the
+// next run.
+type Metrics interface {
+	CountMessages(result string, count int)
+}
+
+func (c *Campaign) MessageTo(contact *Contact) *Message {
+	return &Message{
+		CampaignID:   c.ID,
+		ContactID:    contact.ID,
+		To:           contact.Email,
+		Subject:      c.Name,
+		Body:         c.Content,
+		MaxPerSecond: c.MaxPerSecond,
+		Substitutions: map[string]string{
+			"email":      contact.Email,
+			"contact_id": contact.ID,
+		},
+	}
+}
+
+// RenderedBody returns the body with the substitutions of the recipient
+// applied, for transports that send one message at a time.
+func (m *Message) RenderedBody() string {
+	pairs := make([]string, 0, len(m.Substitutions)*2)
+	for name, value := range m.Substitutions {
+		pairs = append(pairs, "{{"+name+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(m.Body)
+}
@@ -0,0 +1,355 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// The types of the events of a campaign being sent.
+const (
+	// ProgressStatus is sent when the send starts and when it ends, Done or
+	// back to Pending.
+	ProgressStatus = "status"
+	// ProgressBatch is sent after every batch handed to the Mailer.
+	ProgressBatch = "progress"
+)
+
+const (
+	// progressKept is how many events of a campaign are kept for the
+	// clients resuming their stream.
+	progressKept = 100
+	// progressBuffer is how many events a client may fall behind before its
+	// stream is closed, it resumes from the kept ones on reconnect.
+	progressBuffer = 32
+	// progressEvery is how many contacts make a batch for the Mailers
+	// sending one message at a time.
+	progressEvery = 100
+	// progressPoll is the default of ServiceImp.ProgressPoll.
+	progressPoll = 2 * time.Second
+)
+
+// ErrFollowUnavailable is returned when following a campaign without a
+// Progress to publish its events.
+var ErrFollowUnavailable = errors.New("following campaigns needs the progress events")
+
+// ProgressEvent is a change of a campaign being sent, with the totals of its
+// contacts at that point.
+type ProgressEvent struct {
+	// ID orders the events, a client resumes after the last one it got.
+	ID         string
+	Type       string
+	CampaignID string
+	Status     string
+	Total      int64
+	Sent       int64
+	Failed     int64
+}
+
+// Progress relays the events of the campaigns sent by this process to the
+// clients following them, and the state polled from the database of the ones
+// sent by other processes. The last events of every campaign still sending
+// are kept so a client reconnecting gets the ones it missed.
+type Progress struct {
+	// run makes the ids of this process unique, an id of another process or
+	// of before a restart cannot be resumed.
+	run       string
+	mutex     sync.Mutex
+	sequence  int64
+	campaigns map[string]*progressLog
+	closed    bool
+}
+
+type progressLog struct {
+	campaignID string
+	// since is the sequence of the last event dropped, or of the last one
+	// of any campaign when the log was made. A client can resume from it.
+	since     int64
+	events    []ProgressEvent
+	sequences []int64
+	followers map[chan ProgressEvent]struct{}
+	// state is the last known state of the campaign, a polled one is only
+	// published when it differs.
+	state *ProgressEvent
+	// polled is set while a poll of the campaign runs.
+	polled bool
+}
+
+func NewProgress() *Progress {
+	return &Progress{run: xid.New().String(), campaigns: map[string]*progressLog{}}
+}
+
+// ProgressStream is a client following a campaign. Missed holds the events
+// to send first, Events the next ones. Events is closed when the client
+// fell too far behind or the Progress closed.
+type ProgressStream struct {
+	Missed []ProgressEvent
+	Events <-chan ProgressEvent
+
+	progress   *Progress
+	campaignID string
+	events     chan ProgressEvent
+	// at is the id of the last event published when the stream began.
+	at string
+}
+
+// Publish numbers the event and hands it to the followers of its campaign.
+// The log of a campaign no longer sending is dropped with its last follower.
+func (p *Progress) Publish(event ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.publish(p.log(event.CampaignID), event)
+}
+
+func (p *Progress) publish(log *progressLog, event ProgressEvent) {
+	p.sequence++
+	event.ID = p.run + "." + strconv.FormatInt(p.sequence, 10)
+	log.events = append(log.events, event)
+	log.sequences = append(log.sequences, p.sequence)
+	if len(log.events) > progressKept {
+		log.since = log.sequences[0]
+		log.events = log.events[1:]
+		log.sequences = log.sequences[1:]
+	}
+	log.state = &event
+
+	for events := range log.followers {
+		select {
+		case events <- event:
+		default:
+			delete(log.followers, events)
+			close(events)
+		}
+	}
+	p.forget(event.CampaignID)
+}
+
+// Follow starts a stream of the events of the campaign. With the id of the
+// last event the client got, the kept events after it are Missed and
+// resumed reports true; false means the client needs the current state.
+func (p *Progress) Follow(campaignID string, lastEventID string) (stream *ProgressStream, resumed bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	events := make(chan ProgressEvent, progressBuffer)
+	stream = &ProgressStream{Events: events, progress: p, campaignID: campaignID, events: events}
+	if p.closed {
+		close(events)
+		return stream, false
+	}
+	log := p.log(campaignID)
+	log.followers[events] = struct{}{}
+	stream.at = p.run + "." + strconv.FormatInt(p.sequence, 10)
+
+	last, ok := p.sequenceOf(lastEventID)
+	if !ok || last < log.since {
+		return stream, false
+	}
+	for i, sequence := range log.sequences {
+		if sequence > last {
+			stream.Missed = append(stream.Missed, log.events[i])
+		}
+	}
+	return stream, true
+}
+
+// snapshot is the event telling the current state of the campaign, for a
+// client that cannot resume.
+func (s *ProgressStream) snapshot(stats *Stats) ProgressEvent {
+	event := statsEvent(stats, ProgressStatus)
+	event.ID = s.at
+	return event
+}
+
+func statsEvent(stats *Stats, eventType string) ProgressEvent {
+	return ProgressEvent{
+		Type:       eventType,
+		CampaignID: stats.CampaignID,
+		Status:     stats.Status,
+		Total:      stats.Total,
+		Sent:       stats.Sent,
+		Failed:     stats.Failed,
+	}
+}
+
+// watch records the state the followers of the campaign started from, unless
+// one is known already. It returns the log to poll when no poll of the
+// campaign runs yet, nil otherwise.
+func (p *Progress) watch(state ProgressEvent) *progressLog {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	log, ok := p.campaigns[state.CampaignID]
+	if p.closed || !ok {
+		return nil
+	}
+	if log.state == nil {
+		log.state = &state
+	}
+	if log.polled || len(log.followers) == 0 {
+		return nil
+	}
+	log.polled = true
+	return log
+}
+
+// refresh publishes the polled state of the campaign of log when it differs
+// from the last one known, nil only checks the followers. It reports false
+// once nobody follows the campaign, the poll then stops.
+func (p *Progress) refresh(log *progressLog, state *ProgressEvent) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed || len(log.followers) == 0 || p.campaigns[log.campaignID] != log {
+		log.polled = false
+		return false
+	}
+	last := log.state
+	if state == nil || (last != nil && last.Status == state.Status && last.Total == state.Total &&
+		last.Sent == state.Sent && last.Failed == state.Failed) {
+		return true
+	}
+	event := *state
+	event.Type = ProgressBatch
+	if last == nil || last.Status != state.Status {
+		event.Type = ProgressStatus
+	}
+	p.publish(log, event)
+	return true
+}
+
+// Close stops the stream, its Events are closed.
+func (s *ProgressStream) Close() {
+	p := s.progress
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	log, ok := p.campaigns[s.campaignID]
+	if !ok {
+		return
+	}
+	if _, ok := log.followers[s.events]; ok {
+		delete(log.followers, s.events)
+		close(s.events)
+	}
+	p.forget(s.campaignID)
+}
+
+// Close ends every stream, meant for the shutdown so the clients reconnect
+// to another process.
+func (p *Progress) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	for _, log := range p.campaigns {
+		for events := range log.followers {
+			close(events)
+		}
+	}
+	p.campaigns = map[string]*progressLog{}
+}
+
+func (p *Progress) log(campaignID string) *progressLog {
+	log, ok := p.campaigns[campaignID]
+	if !ok {
+		log = &progressLog{campaignID: campaignID, since: p.sequence, followers: map[chan ProgressEvent]struct{}{}}
+		p.campaigns[campaignID] = log
+	}
+	return log
+}
+
+// forget drops the log of a campaign nobody follows once its send ended.
+func (p *Progress) forget(campaignID string) {
+	log := p.campaigns[campaignID]
+	if len(log.followers) > 0 {
+		return
+	}
+	if n := len(log.events); n == 0 || log.events[n-1].Status != Started {
+		delete(p.campaigns, campaignID)
+	}
+}
+
+func (p *Progress) sequenceOf(eventID string) (int64, bool) {
+	run, sequence, ok := strings.Cut(eventID, ".")
+	if !ok || run != p.run {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(sequence, 10, 64)
+	return n, err == nil && n <= p.sequence
+}
+
+// publish hands the event of type telling where the send of the campaign is
+// to its followers, when there is a Progress.
+func (s *ServiceImp) publish(campaign *Campaign, tally *progressTally, eventType string) {
+	if s.Progress != nil {
+		s.Progress.Publish(tally.event(campaign, eventType))
+	}
+}
+
+// pollProgress reads the state of the campaign of log every ProgressPoll for
+// its followers, they get the progress of a send run by another process this
+// way. It stops once nobody follows the campaign.
+func (s *ServiceImp) pollProgress(log *progressLog) {
+	interval := s.ProgressPoll
+	if interval <= 0 {
+		interval = progressPoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		var state *ProgressEvent
+		stats, err := s.Repository.GetStats(context.Background(), log.campaignID)
+		if err != nil {
+			s.logger(context.Background()).Warn("polling campaign progress", "campaign_id", log.campaignID, "error", err)
+		} else {
+			event := statsEvent(stats, ProgressBatch)
+			state = &event
+		}
+		if !s.Progress.refresh(log, state) {
+			return
+		}
+	}
+}
+
+// progressTally keeps the totals of the contacts of a campaign being sent,
+// counted once when the send begins and then as the contacts are marked.
+type progressTally struct {
+	total  int64
+	sent   int64
+	failed int64
+}
+
+func tallyProgress(campaign *Campaign) *progressTally {
+	tally := &progressTally{total: int64(len(campaign.Contacts))}
+	for i := range campaign.Contacts {
+		if campaign.Contacts[i].SentOn != nil {
+			tally.sent++
+		}
+		if campaign.Contacts[i].Status == ContactFailed {
+			tally.failed++
+		}
+	}
+	return tally
+}
+
+// event is the event of type telling where the send of the campaign is.
+func (t *progressTally) event(campaign *Campaign, eventType string) ProgressEvent {
+	return ProgressEvent{
+		Type:       eventType,
+		CampaignID: campaign.ID,
+		Status:     campaign.Status,
+		Total:      t.total,
+		Sent:       t.sent,
+		Failed:     t.failed,
+	}
+}
@@ -0,0 +1,95 @@
+package campaign_test
+
+import (
+	"emailn/internal/domain/campaign"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Progress_should_relay_the_events_of_the_followed_campaign(t *testing.T) {
+	assert := assert.New(t)
+	progress := campaign.NewProgress()
+	stream, resumed := progress.Follow("c1", "")
+	defer stream.Close()
+
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c1", Status: campaign.Started})
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c2", Status: campaign.Started})
+
+	assert.False(resumed)
+	event := <-stream.Events
+	assert.Equal("c1", event.CampaignID)
+	assert.NotEmpty(event.ID)
+	assert.Empty(stream.Events)
+}
+
+func Test_Progress_should_resume_after_the_last_event(t *testing.T) {
+	assert := assert.New(t)
+	progress := campaign.NewProgress()
+	first, _ := progress.Follow("c1", "")
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c1", Status: campaign.Started})
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressBatch, CampaignID: "c1", Status: campaign.Started, Sent: 2})
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressBatch, CampaignID: "c1", Status: campaign.Started, Sent: 4})
+	seen := <-first.Events
+	first.Close()
+
+	stream, resumed := progress.Follow("c1", seen.ID)
+	defer stream.Close()
+
+	assert.True(resumed)
+	assert.Len(stream.Missed, 2)
+	assert.Equal(int64(2), stream.Missed[0].Sent)
+	assert.Equal(int64(4), stream.Missed[1].Sent)
+}
+
+func Test_Progress_should_not_resume_an_id_of_another_process(t *testing.T) {
+	progress := campaign.NewProgress()
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c1", Status: campaign.Started})
+
+	stream, resumed := progress.Follow("c1", "cs9g8tbq4l3d6dn8fgq0.1")
+	defer stream.Close()
+
+	assert.False(t, resumed)
+	assert.Empty(t, stream.Missed)
+}
+
+func Test_Progress_should_forget_a_campaign_done_without_followers(t *testing.T) {
+	progress := campaign.NewProgress()
+	first, _ := progress.Follow("c1", "")
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c1", Status: campaign.Started})
+	first.Close()
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c1", Status: campaign.Done})
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressStatus, CampaignID: "c2", Status: campaign.Started})
+
+	stream, resumed := progress.Follow("c1", "")
+	defer stream.Close()
+
+	assert.False(t, resumed)
+}
+
+func Test_Progress_should_close_the_stream_of_a_follower_too_far_behind(t *testing.T) {
+	progress := campaign.NewProgress()
+	stream, _ := progress.Follow("c1", "")
+	defer stream.Close()
+
+	for i := 0; i < 100; i++ {
+		progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressBatch, CampaignID: "c1", Status: campaign.Started})
+	}
+
+	received := 0
+	for range stream.Events {
+		received++
+	}
+	assert.Less(t, received, 100)
+}
+
+func Test_Progress_Close_should_end_every_stream(t *testing.T) {
+	progress := campaign.NewProgress()
+	stream, _ := progress.Follow("c1", "")
+
+	progress.Close()
+
+	_, open := <-stream.Events
+	assert.False(t, open)
+	stream.Close()
+}
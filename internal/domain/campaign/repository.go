@@ -1,9 +1,29 @@
 package campaign
 
+import (
+	"context"
+	"time"
+)
+
 type Repository interface {
-	Create(campaign *Campaign) error
-	Update(campaign *Campaign) error
-	Get() ([]Campaign, error)
-	GetBy(id string) (*Campaign, error)
-	Delete(campaign *Campaign) error
+	Create(ctx context.Context, campaign *Campaign) error
+	Update(ctx context.Context, campaign *Campaign) error
+	Get(ctx context.Context) ([]Campaign, error)
+	GetBy(ctx context.Context, id string) (*Campaign, error)
+	GetByStatus(ctx context.Context, status string) ([]Campaign, error)
+	GetStats(ctx context.Context, id string) (*Stats, error)
+	// Delete marks the campaign deleted, the other reads do not see it
+	// anymore until it is restored with Update.
+	Delete(ctx context.Context, campaign *Campaign) error
+	GetDeletedBy(ctx context.Context, id string) (*Campaign, error)
+	// Purge removes for good the campaigns deleted before deletedBefore and
+	// their contacts, returning how many campaigns were removed.
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// GetOwner returns the CreatedBy of the campaign, deleted or not.
+	GetOwner(ctx context.Context, id string) (string, error)
+	GetContactBy(ctx context.Context, id string) (*Contact, error)
+	UpdateContact(ctx context.Context, contact *Contact) error
+	UpdateContacts(ctx context.Context, contacts []*Contact) error
+	CreateSuppression(ctx context.Context, suppression *Suppression) error
+	GetSuppressedEmails(ctx context.Context, campaignID string) ([]string, error)
 }
@@ -0,0 +1,294 @@
+package campaign
+
+import (
+	"context"
+	"emailn/internal/domain/webhook"
+	internalerrors "emailn/internal/internal-errors"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrShuttingDown = errors.New("the service is shutting down, try again later")
+	// ErrSendInterrupted is returned when the service stopped in the middle
+	// of a campaign. The campaign stays Started and its resume sends it to
+	// the remaining contacts.
+	ErrSendInterrupted = errors.New("campaign sending interrupted, it resumes on the next start")
+	// ErrSendRefused is returned when the Mailer refused the campaign with an
+	// ErrPermanent error. The campaign went back to Pending, sending it again
+	// fails the same way until the transport is fixed.
+	ErrSendRefused = errors.New("the mail transport refused the campaign, check its configuration")
+)
+
+// deliver sends the campaign to the contacts still pending. Every contact is
+// saved as soon as its message is handed to the Mailer, so an interrupted send
+// goes on from the first contact not yet sent. A failed send goes back to
+// Pending, unless retried, where it stays Started and the error is returned
+// for the job queue to try again. A send refused permanently is not retried.
+func (s *ServiceImp) deliver(ctx context.Context, campaign *Campaign, retried bool) error {
+	tally := tallyProgress(campaign)
+	s.publish(campaign, tally, ProgressStatus)
+	var err error
+	if batchMailer, ok := s.Mailer.(BatchMailer); ok {
+		err = s.sendBatches(ctx, campaign, batchMailer, tally)
+	} else {
+		err = s.send(ctx, campaign, tally)
+	}
+	logger := s.logger(ctx).With("campaign_id", campaign.ID, "run_id", campaign.SendRunID)
+	if errors.Is(err, ErrSendInterrupted) {
+		logger.Warn("campaign send interrupted, it resumes on the next start")
+		return err
+	}
+	permanent := errors.Is(err, ErrPermanent)
+	if err != nil && retried && !permanent {
+		logger.Warn("campaign send failed, it is retried", "error", err)
+		return err
+	}
+	if err != nil {
+		// the contacts already sent are saved, starting again only mails the rest;
+		// saved even when ctx was cancelled, as a cancelled send lands here too
+		logger.Error("campaign send failed, back to pending", "error", err)
+		campaign.BackToPending()
+		err = s.saveWithEvent(context.WithoutCancel(ctx), webhook.CampaignFailed, newCampaignEvent(campaign), func(ctx context.Context) error {
+			return s.Repository.Update(ctx, campaign)
+		})
+		if err != nil {
+			logger.Error("saving failed campaign", "error", err)
+		}
+		s.publish(campaign, tally, ProgressStatus)
+		if permanent {
+			return ErrSendRefused
+		}
+		return internalerrors.ErrInternal
+	}
+
+	campaign.Done()
+	err = s.saveWithEvent(ctx, webhook.CampaignFinished, newCampaignEvent(campaign), func(ctx context.Context) error {
+		return s.Repository.Update(ctx, campaign)
+	})
+	if err != nil {
+		logger.Error("finishing campaign", "error", err)
+		return internalerrors.ErrInternal
+	}
+	s.publish(campaign, tally, ProgressStatus)
+	logger.Info("campaign done")
+	return nil
+}
+
+func (s *ServiceImp) send(ctx context.Context, campaign *Campaign, tally *progressTally) error {
+	handled := 0
+	for i := range campaign.Contacts {
+		contact := &campaign.Contacts[i]
+		if err := s.settleInterrupted(ctx, campaign, contact, tally); err != nil {
+			return err
+		}
+		if contact.Status != ContactPending {
+			continue
+		}
+		if s.isStopping() {
+			return ErrSendInterrupted
+		}
+		message := campaign.MessageTo(contact)
+		if err := s.wait(ctx, message); err != nil {
+			return err
+		}
+
+		contact.MarkSending(campaign.SendRunID)
+		if err := s.Repository.UpdateContact(ctx, contact); err != nil {
+			return err
+		}
+		sendCtx, span := tracer.Start(ctx, "campaign.SendMessage", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("campaign.id", campaign.ID), attribute.String("contact.id", contact.ID)))
+		sendCtx, cancel := s.sendContext(sendCtx)
+		err := s.Mailer.Send(sendCtx, message)
+		cancel()
+		endSpan(span, err)
+		if errors.Is(err, ErrRecipientRejected) {
+			s.logger(ctx).Info("recipient rejected", "campaign_id", campaign.ID, "contact_id", contact.ID, "error", err)
+			contact.MarkFailed()
+			tally.failed++
+			s.count(MessageFailed, 1)
+		} else if err != nil {
+			// the Mailer refused the message, it was not sent
+			contact.MarkPending()
+			s.Repository.UpdateContact(context.WithoutCancel(ctx), contact)
+			s.count(MessageRetried, 1)
+			return err
+		} else {
+			contact.MarkSent(message.ProviderMessageID)
+			tally.sent++
+			s.count(MessageSent, 1)
+		}
+		if err := s.Repository.UpdateContact(context.WithoutCancel(ctx), contact); err != nil {
+			return err
+		}
+		if handled++; handled%progressEvery == 0 {
+			s.publish(campaign, tally, ProgressBatch)
+		}
+	}
+	return nil
+}
+
+func (s *ServiceImp) sendBatches(ctx context.Context, campaign *Campaign, mailer BatchMailer, tally *progressTally) error {
+	var contacts []*Contact
+	for i := range campaign.Contacts {
+		if err := s.settleInterrupted(ctx, campaign, &campaign.Contacts[i], tally); err != nil {
+			return err
+		}
+		if campaign.Contacts[i].Status == ContactPending {
+			contacts = append(contacts, &campaign.Contacts[i])
+		}
+	}
+
+	size := max(mailer.BatchSize(), 1)
+	for start := 0; start < len(contacts); start += size {
+		if s.isStopping() {
+			return ErrSendInterrupted
+		}
+		end := min(start+size, len(contacts))
+		batch := contacts[start:end]
+		messages := make([]*Message, 0, len(batch))
+		for _, contact := range batch {
+			message := campaign.MessageTo(contact)
+			if err := s.wait(ctx, message); err != nil {
+				return err
+			}
+			messages = append(messages, message)
+		}
+
+		for _, contact := range batch {
+			contact.MarkSending(campaign.SendRunID)
+		}
+		if err := s.Repository.UpdateContacts(ctx, batch); err != nil {
+			return err
+		}
+		sendCtx, span := tracer.Start(ctx, "campaign.SendBatch", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("campaign.id", campaign.ID), attribute.Int("batch.size", len(messages))))
+		sendCtx, cancel := s.sendContext(sendCtx)
+		err := mailer.SendBatch(sendCtx, messages)
+		cancel()
+		endSpan(span, err)
+		if err != nil {
+			for _, contact := range batch {
+				contact.MarkPending()
+			}
+			s.Repository.UpdateContacts(context.WithoutCancel(ctx), batch)
+			s.count(MessageRetried, len(batch))
+			return err
+		}
+		for i, contact := range batch {
+			contact.MarkSent(messages[i].ProviderMessageID)
+		}
+		tally.sent += int64(len(batch))
+		s.count(MessageSent, len(batch))
+		if err := s.Repository.UpdateContacts(context.WithoutCancel(ctx), batch); err != nil {
+			return err
+		}
+		s.publish(campaign, tally, ProgressBatch)
+	}
+	return nil
+}
+
+// settleInterrupted handles a contact left Sending by an earlier run, whose
+// process stopped between handing the message to the Mailer and saving the
+// result. The Mailer most likely accepted it, so it is taken as sent rather
+// than risking mailing the contact twice.
+func (s *ServiceImp) settleInterrupted(ctx context.Context, campaign *Campaign, contact *Contact, tally *progressTally) error {
+	if contact.Status != ContactSending || contact.SendRunID == campaign.SendRunID {
+		return nil
+	}
+	contact.MarkSent(contact.ProviderMessageID)
+	tally.sent++
+	return s.Repository.UpdateContact(ctx, contact)
+}
+
+func (s *ServiceImp) wait(ctx context.Context, message *Message) error {
+	if s.Throttle == nil {
+		return nil
+	}
+	return s.Throttle.Wait(ctx, message)
+}
+
+// sendContext bounds a call to the Mailer by the SendTimeout.
+func (s *ServiceImp) sendContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.SendTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.SendTimeout)
+}
+
+func (s *ServiceImp) count(result string, count int) {
+	if s.Metrics != nil {
+		s.Metrics.CountMessages(result, count)
+	}
+}
+
+// ResumeStarted queues the send of the campaigns left Started by a previous
+// process to the contacts they did not reach. It is meant to run once at
+// startup. Only the job queue resumes them: its lease keeps a campaign still
+// sending on another replica from being sent twice, which a synchronous
+// resume cannot tell. Without the queue the campaigns are only logged, to be
+// resumed through the API. A campaign failing to resume does not hold back
+// the others, the errors of all of them are returned together.
+func (s *ServiceImp) ResumeStarted(ctx context.Context) error {
+	campaigns, err := s.Repository.GetByStatus(ctx, Started)
+	if err != nil {
+		return internalerrors.ErrInternal
+	}
+	if s.Outbox == nil {
+		for _, started := range campaigns {
+			s.logger(ctx).Warn("campaign left started, resume it once no other process sends it", "campaign_id", started.ID)
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, started := range campaigns {
+		if err := s.Resume(ctx, started.ID); err != nil {
+			s.logger(ctx).Error("resuming started campaign", "campaign_id", started.ID, "error", err)
+			errs = append(errs, fmt.Errorf("campaign %s: %w", started.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown stops every send after the message in flight and waits until they
+// saved their progress or ctx is done. Starts after Shutdown are refused.
+func (s *ServiceImp) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.stopping = true
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.sending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ServiceImp) beginSending() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopping {
+		return false
+	}
+	s.sending.Add(1)
+	return true
+}
+
+func (s *ServiceImp) isStopping() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.stopping
+}
@@ -1,45 +1,102 @@
 package campaign
 
 import (
+	"context"
 	"emailn/internal/contract"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
 	internalerrors "emailn/internal/internal-errors"
+	"emailn/internal/logging"
 	"errors"
-	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+// ErrStatusInvalid is returned when the campaign is not in the status the
+// operation requires, like starting a campaign that is already done.
+var ErrStatusInvalid = errors.New("Campaign status invalid")
+
 type Service interface {
-	Create(newCampaign contract.NewCampaign) (string, error)
-	GetBy(id string) (*contract.CampaignResponse, error)
-	Delete(id string) error
-	Start(id string) error
+	Create(ctx context.Context, newCampaign contract.NewCampaign) (string, error)
+	GetBy(ctx context.Context, id string) (*contract.CampaignResponse, error)
+	GetStats(ctx context.Context, id string) (*contract.CampaignStatsResponse, error)
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Start(ctx context.Context, id string) error
+	Resume(ctx context.Context, id string) error
+	Schedule(ctx context.Context, id string, at time.Time) error
+	Follow(ctx context.Context, id string, lastEventID string) (*ProgressStream, error)
+	ProcessBounce(ctx context.Context, bounce Bounce) error
 }
 
 type ServiceImp struct {
 	Repository Repository
-	SendMail   func(campaign *Campaign) error
+	Mailer     Mailer
+	// Throttle is optional, without it messages are sent as fast as the
+	// Mailer accepts them.
+	Throttle Throttle
+	// Metrics is optional.
+	Metrics Metrics
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+	// Jobs is optional, it lets the sends be scheduled.
+	Jobs JobQueue
+	// Outbox and Transactions are optional, with them the sends run on the
+	// job queue instead of in the request that starts them. The message
+	// queueing a send is written in the transaction of the status change,
+	// as are the webhook events of the campaign.
+	Outbox       outbox.Repository
+	Transactions outbox.UnitOfWork
+	// Progress is optional, it relays the sends to the clients following
+	// them.
+	Progress *Progress
+	// ProgressPoll is how often a followed campaign is read from the database
+	// for the sends run by other processes, defaults to 2s.
+	ProgressPoll time.Duration
+	// SendTimeout bounds every call to the Mailer, 0 leaves it to the context
+	// of the operation.
+	SendTimeout time.Duration
+
+	mutex    sync.Mutex
+	stopping bool
+	sending  sync.WaitGroup
 }
 
-func (s *ServiceImp) Create(newCampaign contract.NewCampaign) (string, error) {
+func (s *ServiceImp) Create(ctx context.Context, newCampaign contract.NewCampaign) (string, error) {
+	ctx, span := tracer.Start(ctx, "campaign.Create")
+	defer span.End()
 
 	campaign, err := NewCampaign(newCampaign.Name, newCampaign.Content, newCampaign.Emails, newCampaign.CreatedBy)
 	if err != nil {
 		return "", err
 	}
-	err = s.Repository.Create(campaign)
+	err = campaign.LimitRate(newCampaign.MaxPerSecond)
+	if err != nil {
+		return "", err
+	}
+	err = s.Repository.Create(ctx, campaign)
 	if err != nil {
+		s.logger(ctx).Error("creating campaign", "error", err)
 		return "", internalerrors.ErrInternal
 	}
+	span.SetAttributes(attribute.String("campaign.id", campaign.ID), attribute.Int("campaign.contacts", len(campaign.Contacts)))
+	s.logger(ctx).Info("campaign created", "campaign_id", campaign.ID, "email", campaign.CreatedBy, "contacts", len(campaign.Contacts))
 	return campaign.ID, nil
 }
 
-func (s *ServiceImp) GetBy(id string) (*contract.CampaignResponse, error) {
-	fmt.Println("SERVICE:" + id)
-	campaign, err := s.Repository.GetBy(id)
+func (s *ServiceImp) GetBy(ctx context.Context, id string) (*contract.CampaignResponse, error) {
+	ctx, span := tracer.Start(ctx, "campaign.GetBy", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer span.End()
+	campaign, err := s.Repository.GetBy(ctx, id)
 
 	if err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger(ctx).Error("getting campaign", "campaign_id", id, "error", err)
 			return nil, internalerrors.ErrInternal
 		}
 		return nil, internalerrors.ProcessErrorToReturn(err)
@@ -54,54 +111,307 @@ func (s *ServiceImp) GetBy(id string) (*contract.CampaignResponse, error) {
 		Status:               campaign.Status,
 		AmountOfEmailsToSend: len(campaign.Contacts),
 		CreatedBy:            campaign.CreatedBy,
+		Version:              campaign.Version,
 	}, nil
 
 }
 
-func (s *ServiceImp) Delete(id string) error {
-	campaign, err := s.Repository.GetBy(id)
+func (s *ServiceImp) GetStats(ctx context.Context, id string) (*contract.CampaignStatsResponse, error) {
+	_, span := tracer.Start(ctx, "campaign.GetStats", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer span.End()
+	stats, err := s.Repository.GetStats(ctx, id)
+	if err != nil {
+		return nil, internalerrors.ProcessErrorToReturn(err)
+	}
+
+	return &contract.CampaignStatsResponse{
+		ID:           stats.CampaignID,
+		Status:       stats.Status,
+		Total:        stats.Total,
+		Sent:         stats.Sent,
+		Failed:       stats.Failed,
+		Bounced:      stats.Bounced,
+		Suppressed:   stats.Suppressed,
+		Opened:       stats.Opened,
+		Clicked:      stats.Clicked,
+		Unsubscribed: stats.Unsubscribed,
+		StartedOn:    stats.StartedOn,
+		FinishedOn:   stats.FinishedOn,
+		Throughput:   stats.Throughput(time.Now()),
+	}, nil
+}
+
+func (s *ServiceImp) Delete(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "campaign.Delete", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer span.End()
+	campaign, err := s.Repository.GetBy(ctx, id)
 
 	if err != nil {
 		return internalerrors.ProcessErrorToReturn(err)
 	}
 
+	if err := checkVersion(ctx, campaign); err != nil {
+		return err
+	}
 	if campaign.Status != Peding {
-		return errors.New("Campaign status invalid")
+		return ErrStatusInvalid
 	}
 	campaign.Delete()
-	err = s.Repository.Delete(campaign)
+	err = s.Repository.Delete(ctx, campaign)
 	if err != nil {
-		return internalerrors.ErrInternal
+		s.logger(ctx).Error("deleting campaign", "campaign_id", id, "error", err)
+		return updateError(err)
+	}
+	s.logger(ctx).Info("campaign deleted", "campaign_id", id)
+
+	return nil
+
+}
+
+// Restore undoes the Delete of a campaign not purged yet.
+func (s *ServiceImp) Restore(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "campaign.Restore", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer span.End()
+	campaign, err := s.Repository.GetDeletedBy(ctx, id)
+
+	if err != nil {
+		return internalerrors.ProcessErrorToReturn(err)
+	}
+
+	if err := checkVersion(ctx, campaign); err != nil {
+		return err
+	}
+	if campaign.Status != Deleted {
+		return ErrStatusInvalid
+	}
+	campaign.Restore()
+	err = s.Repository.Update(ctx, campaign)
+	if err != nil {
+		s.logger(ctx).Error("restoring campaign", "campaign_id", id, "error", err)
+		return updateError(err)
 	}
+	s.logger(ctx).Info("campaign restored", "campaign_id", id)
 
 	return nil
+}
+
+// Purge removes for good the campaigns deleted more than retention ago.
+func (s *ServiceImp) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, span := tracer.Start(ctx, "campaign.Purge")
+	defer span.End()
 
+	purged, err := s.Repository.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		s.logger(ctx).Error("purging deleted campaigns", "error", err)
+		return 0, internalerrors.ErrInternal
+	}
+	span.SetAttributes(attribute.Int64("campaign.purged", purged))
+	if purged > 0 {
+		s.logger(ctx).Info("deleted campaigns purged", "count", purged, "retention", retention.String())
+	}
+	return purged, nil
 }
 
-func (s *ServiceImp) Start(id string) error {
-	campaignSaved, err := s.Repository.GetBy(id)
+func (s *ServiceImp) Start(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "campaign.Start", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer func() { endSpan(span, err) }()
+
+	if s.Outbox != nil {
+		return s.queueSend(ctx, id, Peding)
+	}
+	if !s.beginSending() {
+		return ErrShuttingDown
+	}
+	defer s.sending.Done()
+
+	campaignSaved, err := s.Repository.GetBy(ctx, id)
 
 	if err != nil {
 		return internalerrors.ProcessErrorToReturn(err)
 	}
 
+	if err := checkVersion(ctx, campaignSaved); err != nil {
+		return err
+	}
 	if campaignSaved.Status != Peding {
-		return errors.New("Campaign status invalid")
+		return ErrStatusInvalid
+	}
+
+	if err := s.start(ctx, campaignSaved); err != nil {
+		return updateError(err)
+	}
+	return s.deliver(ctx, campaignSaved, false)
+}
+
+// Schedule queues the start of a Pending campaign for the given time.
+func (s *ServiceImp) Schedule(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "campaign.Schedule", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer func() { endSpan(span, err) }()
+
+	if s.Jobs == nil {
+		return ErrSchedulingUnavailable
+	}
+	return s.enqueueSend(ctx, id, at)
+}
+
+// Resume sends a Started campaign, interrupted by a shutdown or by a failure
+// after the messages went out, to the contacts that were not mailed yet.
+func (s *ServiceImp) Resume(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "campaign.Resume", trace.WithAttributes(attribute.String("campaign.id", id)))
+	defer func() { endSpan(span, err) }()
+
+	if s.Outbox != nil {
+		return s.queueSend(ctx, id, Started)
 	}
+	if !s.beginSending() {
+		return ErrShuttingDown
+	}
+	defer s.sending.Done()
+
+	campaignSaved, err := s.Repository.GetBy(ctx, id)
 
-	err = s.SendMail(campaignSaved)
 	if err != nil {
-		return internalerrors.ErrInternal
+		return internalerrors.ProcessErrorToReturn(err)
+	}
+
+	if err := checkVersion(ctx, campaignSaved); err != nil {
+		return err
+	}
+	if campaignSaved.Status != Started {
+		return ErrStatusInvalid
+	}
+
+	if err := s.resume(ctx, campaignSaved); err != nil {
+		return updateError(err)
+	}
+	return s.deliver(ctx, campaignSaved, false)
+}
+
+// Follow streams the events of the campaign being sent. The stream starts
+// with the current state of the campaign, unless the client resumes after
+// lastEventID and only gets the events it missed. The campaign is polled
+// while followed, its send may run on another process.
+func (s *ServiceImp) Follow(ctx context.Context, id string, lastEventID string) (*ProgressStream, error) {
+	if s.Progress == nil {
+		return nil, ErrFollowUnavailable
+	}
+	// following first so no event falls between the state and the stream
+	stream, resumed := s.Progress.Follow(id, lastEventID)
+	stats, err := s.Repository.GetStats(ctx, id)
+	if err != nil {
+		stream.Close()
+		return nil, internalerrors.ProcessErrorToReturn(err)
+	}
+	snapshot := stream.snapshot(stats)
+	if !resumed {
+		stream.Missed = []ProgressEvent{snapshot}
+	}
+	if log := s.Progress.watch(snapshot); log != nil {
+		go s.pollProgress(log)
+	}
+	return stream, nil
+}
+
+// start moves a Pending campaign to Started, leaving the suppressed contacts
+// out of the send.
+func (s *ServiceImp) start(ctx context.Context, campaign *Campaign) error {
+	suppressed, err := s.Repository.GetSuppressedEmails(ctx, campaign.ID)
+	if err != nil {
+		s.logger(ctx).Error("getting suppressed emails", "campaign_id", campaign.ID, "error", err)
+		return err
+	}
+	campaign.Suppress(suppressed)
+
+	campaign.Start()
+	err = s.Repository.Update(ctx, campaign)
+	if err != nil {
+		s.logger(ctx).Error("starting campaign", "campaign_id", campaign.ID, "error", err)
+		return err
 	}
+	s.logger(ctx).Info("campaign started", "campaign_id", campaign.ID, "run_id", campaign.SendRunID,
+		"contacts", len(campaign.Contacts), "suppressed", len(suppressed))
+	return nil
+}
+
+func (s *ServiceImp) resume(ctx context.Context, campaign *Campaign) error {
+	campaign.Resume()
+	err := s.Repository.Update(ctx, campaign)
+	if err != nil {
+		s.logger(ctx).Error("resuming campaign", "campaign_id", campaign.ID, "error", err)
+		return err
+	}
+	s.logger(ctx).Info("campaign resumed", "campaign_id", campaign.ID, "run_id", campaign.SendRunID)
+	return nil
+}
 
-	campaignSaved.Done()
-	fmt.Println(campaignSaved.Status)
+func (s *ServiceImp) ProcessBounce(ctx context.Context, bounce Bounce) error {
+	ctx, span := tracer.Start(ctx, "campaign.ProcessBounce", trace.WithAttributes(attribute.String("contact.id", bounce.ContactID)))
+	defer span.End()
 
-	err = s.Repository.Update(campaignSaved)
+	if !bounce.Failed() || bounce.ContactID == "" {
+		return nil
+	}
 
+	contact, err := s.Repository.GetContactBy(ctx, bounce.ContactID)
 	if err != nil {
+		return internalerrors.ProcessErrorToReturn(err)
+	}
+
+	contact.MarkBounced()
+	event := bounceEvent{CampaignID: contact.CampaignId, ContactID: contact.ID, Email: contact.Email,
+		Status: bounce.Status, Hard: bounce.Hard()}
+	if s.Outbox != nil {
+		// the event only goes to the webhooks of the owner of the campaign
+		event.CreatedBy, err = s.Repository.GetOwner(ctx, contact.CampaignId)
+		if err != nil {
+			s.logger(ctx).Error("getting campaign owner", "campaign_id", contact.CampaignId, "error", err)
+			return internalerrors.ErrInternal
+		}
+	}
+	if err := s.saveBounce(ctx, contact, bounce, event); err != nil {
+		s.logger(ctx).Error("saving bounce", "contact_id", contact.ID, "error", err)
 		return internalerrors.ErrInternal
 	}
+	s.logger(ctx).Info("bounce processed", "campaign_id", contact.CampaignId, "contact_id", contact.ID,
+		"status", bounce.Status, "hard", bounce.Hard())
 
 	return nil
 }
+
+// saveBounce saves the bounced contact and, for a hard bounce, puts its
+// address on the suppression list, sending ContactBounced and
+// ContactSuppressed in the same transaction.
+func (s *ServiceImp) saveBounce(ctx context.Context, contact *Contact, bounce Bounce, event bounceEvent) error {
+	save := func(ctx context.Context) error {
+		if err := s.Repository.UpdateContact(ctx, contact); err != nil {
+			return err
+		}
+		if s.Outbox != nil {
+			if err := s.writeEvent(ctx, webhook.ContactBounced, event); err != nil {
+				return err
+			}
+		}
+		if !bounce.Hard() {
+			return nil
+		}
+		suppression := NewSuppression(contact.Email, bounce.Status+" "+bounce.Diagnostic)
+		if err := s.Repository.CreateSuppression(ctx, suppression); err != nil {
+			return err
+		}
+		if s.Outbox == nil {
+			return nil
+		}
+		return s.writeEvent(ctx, webhook.ContactSuppressed, suppressionEvent{CampaignID: contact.CampaignId,
+			ContactID: contact.ID, Email: suppression.Email, Reason: suppression.Reason, CreatedBy: event.CreatedBy})
+	}
+	if s.Outbox == nil {
+		return save(ctx)
+	}
+	return s.Transactions.Do(ctx, save)
+}
+
+// logger returns the logger of the request in ctx, carrying its request id,
+// or the Logger of the service.
+func (s *ServiceImp) logger(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.Logger)
+}
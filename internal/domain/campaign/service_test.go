@@ -1,12 +1,20 @@
 package campaign_test
 
 import (
+	"context"
 	"emailn/internal/contract"
 	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
 	internalerrors "emailn/internal/internal-errors"
 	internalmock "emailn/internal/test/internal-mock"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -23,6 +31,7 @@ var (
 	campaignPedenting *campaign.Campaign
 	campaignStarted   *campaign.Campaign
 	repositoryMock    *internalmock.CampaignRepositoryMock
+	mailerMock        *internalmock.MailerMock
 	service           = campaign.ServiceImp{}
 )
 
@@ -31,13 +40,15 @@ func setUp() {
 	campaignStarted = &campaign.Campaign{ID: "1", Status: campaign.Started}
 	repositoryMock = new(internalmock.CampaignRepositoryMock)
 	service.Repository = repositoryMock
+	mailerMock = new(internalmock.MailerMock)
+	service.Mailer = mailerMock
 }
 
 func Test_Create_Campaign(t *testing.T) {
 	setUp()
 	assert := assert.New(t)
 	repositoryMock.On("Create", mock.Anything).Return(nil)
-	id, err := service.Create(newCampaign)
+	id, err := service.Create(context.Background(), newCampaign)
 
 	assert.NotNil(id)
 	assert.Nil(err)
@@ -46,7 +57,7 @@ func Test_Create_Campaign(t *testing.T) {
 
 func Test_Create_ValidateDomainError(t *testing.T) {
 	assert := assert.New(t)
-	_, err := service.Create(contract.NewCampaign{})
+	_, err := service.Create(context.Background(), contract.NewCampaign{})
 	assert.False(errors.Is(internalerrors.ErrInternal, err))
 
 }
@@ -62,7 +73,7 @@ func Test_Create_SaveCampaign(t *testing.T) {
 			}
 			return true
 		})).Return(nil)
-	service.Create(newCampaign)
+	service.Create(context.Background(), newCampaign)
 	repositoryMock.AssertExpectations(t)
 }
 
@@ -71,7 +82,7 @@ func Test_Create_ValidateRepositorySave(t *testing.T) {
 	assert := assert.New(t)
 	repositoryMock.On("Create", mock.Anything).Return(errors.New("error to save on database"))
 
-	_, err := service.Create(newCampaign)
+	_, err := service.Create(context.Background(), newCampaign)
 
 	assert.True(errors.Is(internalerrors.ErrInternal, err))
 
@@ -83,7 +94,7 @@ func Test_GetById_returnCampaign(t *testing.T) {
 	repositoryMock.On("GetBy", mock.MatchedBy(func(id string) bool {
 		return id == campaignPedenting.ID
 	})).Return(campaignPedenting, nil)
-	campaignReturned, _ := service.GetBy(campaignPedenting.ID)
+	campaignReturned, _ := service.GetBy(context.Background(), campaignPedenting.ID)
 	assert.Equal(campaignPedenting.ID, campaignReturned.ID)
 	assert.Equal(campaignPedenting.Name, campaignReturned.Name)
 	assert.Equal(campaignPedenting.Status, campaignReturned.Status)
@@ -96,7 +107,7 @@ func Test_GetById_returnErrorWhenSomethingWrongExist(t *testing.T) {
 	setUp()
 	assert := assert.New(t)
 	repositoryMock.On("GetBy", mock.Anything).Return(nil, errors.New("Something wrong"))
-	_, err := service.GetBy(campaignPedenting.ID)
+	_, err := service.GetBy(context.Background(), campaignPedenting.ID)
 	assert.Equal(internalerrors.ErrInternal.Error(), err.Error())
 }
 
@@ -105,7 +116,7 @@ func Test_Delete_returnRecordNotFound_when_campaign_does_not_exist(t *testing.T)
 	assert := assert.New(t)
 	campaignIDInvalid := "invalid"
 	repositoryMock.On("GetBy", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
-	err := service.Delete(campaignIDInvalid)
+	err := service.Delete(context.Background(), campaignIDInvalid)
 	assert.Equal(err.Error(), gorm.ErrRecordNotFound.Error())
 
 }
@@ -114,7 +125,7 @@ func Test_Delete_returnStatusInvalid_when_campaign_has_status_not_equals_pending
 	setUp()
 	assert := assert.New(t)
 	repositoryMock.On("GetBy", mock.Anything).Return(campaignStarted, nil)
-	err := service.Delete(campaignStarted.ID)
+	err := service.Delete(context.Background(), campaignStarted.ID)
 	assert.Equal("Campaign status invalid", err.Error())
 
 }
@@ -125,7 +136,7 @@ func Test_Delete_returnInternalError_when_delete_has_problem(t *testing.T) {
 	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
 	repositoryMock.On("Delete", mock.Anything).Return(errors.New("error to delete campaign"))
 	service.Repository = repositoryMock
-	err := service.Delete(campaignPedenting.ID)
+	err := service.Delete(context.Background(), campaignPedenting.ID)
 	assert.Equal(internalerrors.ErrInternal.Error(), err.Error())
 
 }
@@ -137,17 +148,79 @@ func Test_Delete_returnINil_when_delete_has_success(t *testing.T) {
 	repositoryMock.On("Delete", mock.MatchedBy(func(campaign *campaign.Campaign) bool {
 		return campaignPedenting == campaign
 	})).Return(nil)
-	err := service.Delete(campaignPedenting.ID)
+	err := service.Delete(context.Background(), campaignPedenting.ID)
 	assert.Nil(err)
 
 }
 
+func Test_Delete_should_keep_the_campaign_marked_deleted(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("Delete", mock.Anything).Return(nil)
+
+	service.Delete(context.Background(), campaignPedenting.ID)
+
+	assert.Equal(campaign.Deleted, campaignPedenting.Status)
+	assert.NotNil(campaignPedenting.DeletedOn)
+}
+
+func Test_Restore_returnRecordNotFound_when_campaign_is_not_deleted(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetDeletedBy", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	err := service.Restore(context.Background(), campaignPedenting.ID)
+
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func Test_Restore_should_bring_the_campaign_back_to_pending(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignPedenting.Delete()
+	repositoryMock.On("GetDeletedBy", campaignPedenting.ID).Return(campaignPedenting, nil)
+	repositoryMock.On("Update", mock.MatchedBy(func(restored *campaign.Campaign) bool {
+		return restored.Status == campaign.Peding && restored.DeletedOn == nil
+	})).Return(nil)
+
+	err := service.Restore(context.Background(), campaignPedenting.ID)
+
+	assert.Nil(err)
+	repositoryMock.AssertExpectations(t)
+}
+
+func Test_Restore_returnInternalError_when_update_has_problem(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignPedenting.Delete()
+	repositoryMock.On("GetDeletedBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("Update", mock.Anything).Return(errors.New("error to update campaign"))
+
+	err := service.Restore(context.Background(), campaignPedenting.ID)
+
+	assert.Equal(internalerrors.ErrInternal, err)
+}
+
+func Test_Purge_should_remove_campaigns_deleted_before_the_retention(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("Purge", mock.MatchedBy(func(deletedBefore time.Time) bool {
+		return time.Since(deletedBefore) >= 24*time.Hour && time.Since(deletedBefore) < 25*time.Hour
+	})).Return(int64(2), nil)
+
+	purged, err := service.Purge(context.Background(), 24*time.Hour)
+
+	assert.Nil(err)
+	assert.Equal(int64(2), purged)
+}
+
 func Test_Start_returnRecordNotFound_when_campaign_does_not_exist(t *testing.T) {
 	setUp()
 	assert := assert.New(t)
 	campaignIDInvalid := "invalid"
 	repositoryMock.On("GetBy", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
-	err := service.Start(campaignIDInvalid)
+	err := service.Start(context.Background(), campaignIDInvalid)
 	assert.Equal(err.Error(), gorm.ErrRecordNotFound.Error())
 
 }
@@ -156,7 +229,7 @@ func Test_Start_returnStatusInvalid_when_campaign_has_status_not_equals_pending(
 	setUp()
 	assert := assert.New(t)
 	repositoryMock.On("GetBy", mock.Anything).Return(campaignStarted, nil)
-	err := service.Start(campaignStarted.ID)
+	err := service.Start(context.Background(), campaignStarted.ID)
 	assert.Equal("Campaign status invalid", err.Error())
 
 }
@@ -165,32 +238,31 @@ func Test_Start_should_send_mail(t *testing.T) {
 	setUp()
 	assert := assert.New(t)
 	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
 	repositoryMock.On("Update", mock.Anything).Return(nil)
-	emailWasSend := false
-	sendMail := func(campaign *campaign.Campaign) error {
-		if campaign.ID == campaignPedenting.ID {
-			emailWasSend = true
-		}
-
-		return nil
-	}
-	service.SendMail = sendMail
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.MatchedBy(func(message *campaign.Message) bool {
+		return message.CampaignID == campaignPedenting.ID &&
+			message.ContactID == campaignPedenting.Contacts[0].ID &&
+			message.To == campaignPedenting.Contacts[0].Email
+	})).Return(nil)
 
-	service.Start(campaignPedenting.ID)
-	assert.True(emailWasSend)
+	err := service.Start(context.Background(), campaignPedenting.ID)
+	assert.Nil(err)
+	mailerMock.AssertExpectations(t)
 
 }
 
-func Test_Start_ReturnError_when_func_SencMail_fail(t *testing.T) {
+func Test_Start_ReturnError_when_Mailer_fail(t *testing.T) {
 	setUp()
 	assert := assert.New(t)
 	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
-	sendMail := func(campaign *campaign.Campaign) error {
-		return errors.New("error to send mail")
-	}
-	service.SendMail = sendMail
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(errors.New("error to send mail"))
 
-	err := service.Start(campaignPedenting.ID)
+	err := service.Start(context.Background(), campaignPedenting.ID)
 	assert.Equal(internalerrors.ErrInternal.Error(), err.Error())
 
 }
@@ -200,16 +272,869 @@ func Test_Start_ReturnNil_when_updated_to_done(t *testing.T) {
 	assert := assert.New(t)
 
 	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	repositoryMock.On("Update", mock.MatchedBy(func(campaignToUpdate *campaign.Campaign) bool {
+		return campaignToUpdate.Status == campaign.Started
+	})).Return(nil)
 	repositoryMock.On("Update", mock.MatchedBy(func(campaignToUpdate *campaign.Campaign) bool {
 		return campaignPedenting.ID == campaignToUpdate.ID && campaignToUpdate.Status == campaign.Done
 	})).Return(nil)
 
-	sendMail := func(campaign *campaign.Campaign) error {
-		return nil
+	mailerMock.On("Send", mock.Anything).Return(nil)
+
+	service.Start(context.Background(), campaignPedenting.ID)
+	assert.Equal(campaign.Done, campaignPedenting.Status)
+
+}
+
+func Test_Start_should_mark_contacts_as_sent(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil)
+
+	service.Start(context.Background(), campaignPedenting.ID)
+	assert.NotNil(campaignPedenting.StartedOn)
+	assert.NotNil(campaignPedenting.FinishedOn)
+	for _, contact := range campaignPedenting.Contacts {
+		assert.Equal(campaign.ContactSent, contact.Status)
+		assert.NotNil(contact.SentOn)
+	}
+}
+
+func Test_GetStats_returnStats(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	startedOn := time.Now().Add(-10 * time.Second)
+	finishedOn := startedOn.Add(5 * time.Second)
+	stats := &campaign.Stats{
+		CampaignID: campaignPedenting.ID,
+		Status:     campaign.Done,
+		StartedOn:  &startedOn,
+		FinishedOn: &finishedOn,
+		Total:      12,
+		Sent:       10,
+		Failed:     2,
+		Bounced:    1,
+	}
+	repositoryMock.On("GetStats", campaignPedenting.ID).Return(stats, nil)
+
+	response, err := service.GetStats(context.Background(), campaignPedenting.ID)
+
+	assert.Nil(err)
+	assert.Equal(campaignPedenting.ID, response.ID)
+	assert.Equal(int64(12), response.Total)
+	assert.Equal(int64(10), response.Sent)
+	assert.Equal(int64(2), response.Failed)
+	assert.Equal(int64(1), response.Bounced)
+	assert.InDelta(2.0, response.Throughput, 0.001)
+}
+
+func Test_GetStats_returnRecordNotFound_when_campaign_does_not_exist(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetStats", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	_, err := service.GetStats(context.Background(), "invalid")
+	assert.Equal(gorm.ErrRecordNotFound.Error(), err.Error())
+}
+
+func Test_GetStats_returnInternalError_when_repository_fails(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetStats", mock.Anything).Return(nil, errors.New("Something wrong"))
+	_, err := service.GetStats(context.Background(), campaignPedenting.ID)
+	assert.Equal(internalerrors.ErrInternal.Error(), err.Error())
+}
+
+func Test_Start_should_not_send_to_suppressed_contacts(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", campaignPedenting.ID).Return(newCampaign.Emails, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+
+	service.Start(context.Background(), campaignPedenting.ID)
+	mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+	assert.Equal(campaign.ContactSuppressed, campaignPedenting.Contacts[0].Status)
+	assert.Nil(campaignPedenting.Contacts[0].SentOn)
+}
+
+func Test_Start_should_mark_contact_failed_when_recipient_rejected(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(campaign.ErrRecipientRejected)
+
+	err := service.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Nil(err)
+	assert.Equal(campaign.Done, campaignPedenting.Status)
+	assert.Equal(campaign.ContactFailed, campaignPedenting.Contacts[0].Status)
+}
+
+func Test_ProcessBounce_should_mark_contact_bounced_and_suppress_when_hard(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	contact := &campaign.Contact{ID: "c1", Email: "Teste1@test.com", Status: campaign.ContactSent}
+	repositoryMock.On("GetContactBy", "c1").Return(contact, nil)
+	repositoryMock.On("UpdateContact", contact).Return(nil)
+	repositoryMock.On("CreateSuppression", mock.MatchedBy(func(suppression *campaign.Suppression) bool {
+		return suppression.Email == "teste1@test.com"
+	})).Return(nil)
+
+	err := service.ProcessBounce(context.Background(), campaign.Bounce{ContactID: "c1", Action: "failed", Status: "5.1.1"})
+
+	assert.Nil(err)
+	assert.Equal(campaign.ContactBounced, contact.Status)
+	assert.NotNil(contact.BouncedOn)
+	repositoryMock.AssertExpectations(t)
+}
+
+func Test_ProcessBounce_should_write_the_bounce_event_with_the_contact(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	transactions := &internalmock.UnitOfWorkMock{}
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: transactions}
+	contact := &campaign.Contact{ID: "c1", Email: "teste1@test.com", CampaignId: "cp1", Status: campaign.ContactSent}
+	repositoryMock.On("GetContactBy", "c1").Return(contact, nil)
+	repositoryMock.On("GetOwner", "cp1").Return("owner@test.com", nil)
+	repositoryMock.On("UpdateContact", contact).Return(nil)
+	outboxMock.On("Create", mock.MatchedBy(func(message *outbox.Message) bool {
+		var event webhook.Event
+		json.Unmarshal([]byte(message.Payload), &event)
+		return message.Topic == webhook.EventJob && event.Type == webhook.ContactBounced &&
+			event.Owner == "owner@test.com" && strings.Contains(message.Payload, `"email":"teste1@test.com"`)
+	})).Return(nil)
+
+	err := queuedService.ProcessBounce(context.Background(), campaign.Bounce{ContactID: "c1", Action: "failed", Status: "4.2.2"})
+
+	assert.Nil(err)
+	assert.Equal(1, transactions.Transactions)
+	outboxMock.AssertExpectations(t)
+}
+
+func Test_ProcessBounce_should_write_the_suppressed_event_when_hard(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	transactions := &internalmock.UnitOfWorkMock{}
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: transactions}
+	contact := &campaign.Contact{ID: "c1", Email: "Teste1@test.com", CampaignId: "cp1", Status: campaign.ContactSent}
+	repositoryMock.On("GetContactBy", "c1").Return(contact, nil)
+	repositoryMock.On("GetOwner", "cp1").Return("owner@test.com", nil)
+	repositoryMock.On("UpdateContact", contact).Return(nil)
+	repositoryMock.On("CreateSuppression", mock.Anything).Return(nil)
+	eventTypes := []string{}
+	outboxMock.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		var event webhook.Event
+		json.Unmarshal([]byte(args.Get(0).(*outbox.Message).Payload), &event)
+		eventTypes = append(eventTypes, event.Type)
+		if event.Type == webhook.ContactSuppressed {
+			assert.Equal("owner@test.com", event.Owner)
+			assert.Contains(args.Get(0).(*outbox.Message).Payload, `"email":"teste1@test.com"`)
+		}
+	})
+
+	err := queuedService.ProcessBounce(context.Background(), campaign.Bounce{ContactID: "c1", Action: "failed", Status: "5.1.1"})
+
+	assert.Nil(err)
+	assert.Equal([]string{webhook.ContactBounced, webhook.ContactSuppressed}, eventTypes)
+	assert.Equal(1, transactions.Transactions)
+}
+
+func Test_ProcessBounce_should_not_suppress_when_soft(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	contact := &campaign.Contact{ID: "c1", Email: "teste1@test.com", Status: campaign.ContactSent}
+	repositoryMock.On("GetContactBy", "c1").Return(contact, nil)
+	repositoryMock.On("UpdateContact", contact).Return(nil)
+
+	err := service.ProcessBounce(context.Background(), campaign.Bounce{ContactID: "c1", Action: "failed", Status: "4.2.2"})
+
+	assert.Nil(err)
+	assert.Equal(campaign.ContactBounced, contact.Status)
+	repositoryMock.AssertNotCalled(t, "CreateSuppression", mock.Anything)
+}
+
+func Test_ProcessBounce_should_ignore_delayed_notifications(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+
+	err := service.ProcessBounce(context.Background(), campaign.Bounce{ContactID: "c1", Action: "delayed", Status: "4.4.7"})
+
+	assert.Nil(err)
+	repositoryMock.AssertNotCalled(t, "GetContactBy", mock.Anything)
+}
+
+func Test_ProcessBounce_returnRecordNotFound_when_contact_does_not_exist(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetContactBy", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	err := service.ProcessBounce(context.Background(), campaign.Bounce{ContactID: "c1", Action: "failed", Status: "5.1.1"})
+
+	assert.Equal(gorm.ErrRecordNotFound.Error(), err.Error())
+}
+
+func Test_Start_should_send_in_batches_when_mailer_supports_it(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignToSend, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com", "dois@test.com", "tres@test.com"}, newCampaign.CreatedBy)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignToSend, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContacts", mock.Anything).Return(nil)
+	batchMailer := &internalmock.BatchMailerMock{Size: 2}
+	batchMailer.On("SendBatch", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		for _, message := range args.Get(0).([]*campaign.Message) {
+			message.ProviderMessageID = "provider-id"
+		}
+	})
+	service.Mailer = batchMailer
+
+	err := service.Start(context.Background(), campaignToSend.ID)
+
+	assert.Nil(err)
+	batchMailer.AssertNumberOfCalls(t, "SendBatch", 2)
+	for _, contact := range campaignToSend.Contacts {
+		assert.Equal(campaign.ContactSent, contact.Status)
+		assert.Equal("provider-id", contact.ProviderMessageID)
+	}
+}
+
+func Test_Start_should_publish_the_status_and_the_progress_of_every_batch(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignToSend, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com", "dois@test.com", "tres@test.com"}, newCampaign.CreatedBy)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignToSend, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContacts", mock.Anything).Return(nil)
+	batchMailer := &internalmock.BatchMailerMock{Size: 2}
+	batchMailer.On("SendBatch", mock.Anything).Return(nil)
+	progress := campaign.NewProgress()
+	followedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: batchMailer, Progress: progress}
+	stream, _ := progress.Follow(campaignToSend.ID, "")
+
+	err := followedService.Start(context.Background(), campaignToSend.ID)
+
+	assert.Nil(err)
+	stream.Close()
+	var events []campaign.ProgressEvent
+	for event := range stream.Events {
+		events = append(events, event)
+	}
+	assert.Len(events, 4)
+	assert.Equal(campaign.ProgressStatus, events[0].Type)
+	assert.Equal(campaign.Started, events[0].Status)
+	assert.Equal(campaign.ProgressBatch, events[1].Type)
+	assert.Equal(int64(2), events[1].Sent)
+	assert.Equal(int64(3), events[2].Sent)
+	assert.Equal(campaign.ProgressStatus, events[3].Type)
+	assert.Equal(campaign.Done, events[3].Status)
+	assert.Equal(int64(3), events[3].Total)
+}
+
+func Test_Follow_should_start_with_the_state_of_the_campaign(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	followedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock, Progress: campaign.NewProgress()}
+	repositoryMock.On("GetStats", "c1").Return(&campaign.Stats{CampaignID: "c1", Status: campaign.Started, Total: 10, Sent: 4}, nil)
+
+	stream, err := followedService.Follow(context.Background(), "c1", "")
+
+	assert.Nil(err)
+	defer stream.Close()
+	assert.Len(stream.Missed, 1)
+	assert.Equal(campaign.Started, stream.Missed[0].Status)
+	assert.Equal(int64(4), stream.Missed[0].Sent)
+	assert.NotEmpty(stream.Missed[0].ID)
+}
+
+func Test_Follow_should_relay_the_progress_of_a_send_run_by_another_process(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	followedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Progress: campaign.NewProgress(), ProgressPoll: time.Millisecond}
+	repositoryMock.On("GetStats", "c1").Return(&campaign.Stats{CampaignID: "c1", Status: campaign.Started, Total: 10, Sent: 4}, nil).Once()
+	repositoryMock.On("GetStats", "c1").Return(&campaign.Stats{CampaignID: "c1", Status: campaign.Started, Total: 10, Sent: 7}, nil).Once()
+	repositoryMock.On("GetStats", "c1").Return(&campaign.Stats{CampaignID: "c1", Status: campaign.Done, Total: 10, Sent: 10}, nil)
+
+	stream, err := followedService.Follow(context.Background(), "c1", "")
+
+	assert.Nil(err)
+	defer stream.Close()
+	var events []campaign.ProgressEvent
+	for len(events) < 2 {
+		select {
+		case event := <-stream.Events:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatal("no progress polled")
+		}
+	}
+	assert.Equal(campaign.ProgressBatch, events[0].Type)
+	assert.Equal(int64(7), events[0].Sent)
+	assert.Equal(campaign.ProgressStatus, events[1].Type)
+	assert.Equal(campaign.Done, events[1].Status)
+}
+
+func Test_Follow_returnRecordNotFound_when_the_campaign_does_not_exist(t *testing.T) {
+	setUp()
+	followedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock, Progress: campaign.NewProgress()}
+	repositoryMock.On("GetStats", "c1").Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := followedService.Follow(context.Background(), "c1", "")
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func Test_Follow_returnErrFollowUnavailable_without_Progress(t *testing.T) {
+	setUp()
+
+	_, err := service.Follow(context.Background(), "c1", "")
+
+	assert.Equal(t, campaign.ErrFollowUnavailable, err)
+}
+
+func Test_Create_returnError_when_MaxPerSecond_is_negative(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	request := newCampaign
+	request.MaxPerSecond = -1
+
+	_, err := service.Create(context.Background(), request)
+
+	assert.Equal("maxpersecond must not be negative", err.Error())
+}
+
+func Test_Start_should_wait_for_throttle_before_each_message(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignPedenting.MaxPerSecond = 5
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil)
+	throttleMock := new(internalmock.ThrottleMock)
+	throttleMock.On("Wait", mock.MatchedBy(func(message *campaign.Message) bool {
+		return message.MaxPerSecond == 5
+	})).Return(nil)
+	service.Throttle = throttleMock
+	defer func() { service.Throttle = nil }()
+
+	err := service.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Nil(err)
+	throttleMock.AssertNumberOfCalls(t, "Wait", len(campaignPedenting.Contacts))
+}
+
+func Test_Start_should_go_back_to_pending_when_Mailer_fail(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(errors.New("error to send mail"))
+
+	service.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Equal(campaign.Peding, campaignPedenting.Status)
+	assert.Equal(campaign.ContactPending, campaignPedenting.Contacts[0].Status)
+}
+
+func Test_Start_should_stop_after_message_in_flight_when_shutting_down(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignToSend, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com", "dois@test.com"}, newCampaign.CreatedBy)
+	stoppingService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock}
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignToSend, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		go stoppingService.Shutdown(context.Background())
+		time.Sleep(10 * time.Millisecond)
+	})
+
+	err := stoppingService.Start(context.Background(), campaignToSend.ID)
+
+	assert.Equal(campaign.ErrSendInterrupted, err)
+	assert.Equal(campaign.Started, campaignToSend.Status)
+	assert.Equal(campaign.ContactSent, campaignToSend.Contacts[0].Status)
+	assert.Equal(campaign.ContactPending, campaignToSend.Contacts[1].Status)
+	repositoryMock.AssertCalled(t, "UpdateContact", &campaignToSend.Contacts[0])
+}
+
+func Test_Start_returnErrShuttingDown_after_Shutdown(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	stoppedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock}
+	stoppedService.Shutdown(context.Background())
+
+	err := stoppedService.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Equal(campaign.ErrShuttingDown, err)
+	repositoryMock.AssertNotCalled(t, "GetBy", mock.Anything)
+}
+
+func Test_ResumeStarted_should_queue_the_send_of_each_started_campaign(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	transactions := &internalmock.UnitOfWorkMock{}
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: transactions}
+	campaignToResume, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com", "dois@test.com"}, newCampaign.CreatedBy)
+	campaignToResume.Start()
+	repositoryMock.On("GetByStatus", campaign.Started).Return([]campaign.Campaign{{ID: campaignToResume.ID}}, nil)
+	repositoryMock.On("GetBy", campaignToResume.ID).Return(campaignToResume, nil)
+	outboxMock.On("Create", mock.MatchedBy(func(message *outbox.Message) bool {
+		return message.Topic == campaign.SendJob && message.Key == campaign.SendJob+":"+campaignToResume.ID
+	})).Return(nil)
+
+	err := queuedService.ResumeStarted(context.Background())
+
+	assert.Nil(err)
+	outboxMock.AssertExpectations(t)
+	mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+	assert.Equal(campaign.Started, campaignToResume.Status)
+}
+
+func Test_ResumeStarted_should_not_send_without_the_job_queue(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignToResume, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com", "dois@test.com"}, newCampaign.CreatedBy)
+	campaignToResume.Start()
+	repositoryMock.On("GetByStatus", campaign.Started).Return([]campaign.Campaign{{ID: campaignToResume.ID}}, nil)
+
+	err := service.ResumeStarted(context.Background())
+
+	assert.Nil(err)
+	mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+	repositoryMock.AssertNotCalled(t, "Update", mock.Anything)
+	assert.Equal(campaign.Started, campaignToResume.Status)
+}
+
+func Test_ResumeStarted_should_resume_the_other_campaigns_when_one_fails(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: &internalmock.UnitOfWorkMock{}}
+	campaignToResume, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com"}, newCampaign.CreatedBy)
+	campaignToResume.Start()
+	repositoryMock.On("GetByStatus", campaign.Started).Return([]campaign.Campaign{{ID: "gone"}, {ID: campaignToResume.ID}}, nil)
+	repositoryMock.On("GetBy", "gone").Return(nil, gorm.ErrRecordNotFound)
+	repositoryMock.On("GetBy", campaignToResume.ID).Return(campaignToResume, nil)
+	outboxMock.On("Create", mock.Anything).Return(nil)
+
+	err := queuedService.ResumeStarted(context.Background())
+
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	outboxMock.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func Test_Start_should_save_contact_as_sending_before_sending(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	statusesSaved := []string{}
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		statusesSaved = append(statusesSaved, args.Get(0).(*campaign.Contact).Status)
+	})
+	mailerMock.On("Send", mock.Anything).Return(nil)
+
+	service.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Equal([]string{campaign.ContactSending, campaign.ContactSent}, statusesSaved)
+	assert.NotEmpty(campaignPedenting.SendRunID)
+	assert.Equal(campaignPedenting.SendRunID, campaignPedenting.Contacts[0].SendRunID)
+}
+
+func Test_Resume_returnStatusInvalid_when_campaign_is_not_started(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+
+	err := service.Resume(context.Background(), campaignPedenting.ID)
+
+	assert.Equal("Campaign status invalid", err.Error())
+}
+
+func Test_Resume_should_not_mail_contacts_interrupted_while_sending(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	campaignToResume, _ := campaign.NewCampaign(newCampaign.Name, newCampaign.Content,
+		[]string{"um@test.com", "dois@test.com", "tres@test.com"}, newCampaign.CreatedBy)
+	campaignToResume.Start()
+	previousRun := campaignToResume.SendRunID
+	campaignToResume.Contacts[0].MarkSent("")
+	campaignToResume.Contacts[1].MarkSending(previousRun)
+	repositoryMock.On("GetBy", campaignToResume.ID).Return(campaignToResume, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil)
+
+	err := service.Resume(context.Background(), campaignToResume.ID)
+
+	assert.Nil(err)
+	mailerMock.AssertNumberOfCalls(t, "Send", 1)
+	mailerMock.AssertCalled(t, "Send", mock.MatchedBy(func(message *campaign.Message) bool {
+		return message.To == "tres@test.com"
+	}))
+	assert.NotEqual(previousRun, campaignToResume.SendRunID)
+	assert.Equal(campaign.ContactSent, campaignToResume.Contacts[1].Status)
+	assert.Equal(campaign.Done, campaignToResume.Status)
+}
+
+func Test_Start_should_count_sent_messages(t *testing.T) {
+	setUp()
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil)
+	metricsMock := new(internalmock.MetricsMock)
+	metricsMock.On("CountMessages", campaign.MessageSent, 1).Return()
+	service.Metrics = metricsMock
+	defer func() { service.Metrics = nil }()
+
+	service.Start(context.Background(), campaignPedenting.ID)
+
+	metricsMock.AssertExpectations(t)
+}
+
+func Test_Start_should_count_retried_messages_when_Mailer_fail(t *testing.T) {
+	setUp()
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(errors.New("error to send mail"))
+	metricsMock := new(internalmock.MetricsMock)
+	metricsMock.On("CountMessages", campaign.MessageRetried, 1).Return()
+	service.Metrics = metricsMock
+	defer func() { service.Metrics = nil }()
+
+	service.Start(context.Background(), campaignPedenting.ID)
+
+	metricsMock.AssertExpectations(t)
+}
+
+type mailerFunc func(ctx context.Context, message *campaign.Message) error
+
+func (f mailerFunc) Send(ctx context.Context, message *campaign.Message) error {
+	return f(ctx, message)
+}
+
+func Test_Start_should_bound_each_send_by_SendTimeout(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	var deadline time.Time
+	timedService := &campaign.ServiceImp{
+		Repository:  repositoryMock,
+		SendTimeout: time.Minute,
+		Mailer: mailerFunc(func(ctx context.Context, message *campaign.Message) error {
+			deadline, _ = ctx.Deadline()
+			return nil
+		}),
+	}
+
+	err := timedService.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Nil(err)
+	assert.WithinDuration(time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func Test_Start_should_save_progress_when_the_request_is_cancelled(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	cancelledService := &campaign.ServiceImp{
+		Repository: repositoryMock,
+		Mailer: mailerFunc(func(ctx context.Context, message *campaign.Message) error {
+			cancel()
+			return ctx.Err()
+		}),
 	}
-	service.SendMail = sendMail
 
-	service.Start(campaignPedenting.ID)
+	err := cancelledService.Start(ctx, campaignPedenting.ID)
+
+	assert.Equal(internalerrors.ErrInternal, err)
+	assert.Equal(campaign.Peding, campaignPedenting.Status)
+	assert.Equal(campaign.ContactPending, campaignPedenting.Contacts[0].Status)
+	repositoryMock.AssertCalled(t, "Update", campaignPedenting)
+}
+
+func Test_Start_should_start_the_campaign_and_queue_its_send_in_one_transaction(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	transactions := &internalmock.UnitOfWorkMock{}
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: transactions}
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	outboxMock.On("Create", mock.MatchedBy(func(message *outbox.Message) bool {
+		return message.Topic == campaign.SendJob && message.Key == campaign.SendJob+":"+campaignPedenting.ID
+	})).Return(nil)
+	outboxMock.On("Create", mock.MatchedBy(func(message *outbox.Message) bool {
+		return message.Topic == webhook.EventJob && strings.Contains(message.Payload, webhook.CampaignStarted)
+	})).Return(nil)
+
+	err := queuedService.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Nil(err)
+	assert.Equal(campaign.Started, campaignPedenting.Status)
+	assert.Equal(1, transactions.Transactions)
+	outboxMock.AssertExpectations(t)
+	mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+func Test_Start_returnInternalError_when_the_send_is_not_queued(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: &internalmock.UnitOfWorkMock{}}
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	outboxMock.On("Create", mock.Anything).Return(errors.New("error to save on database"))
+
+	err := queuedService.Start(context.Background(), campaignPedenting.ID)
+
+	assert.Equal(internalerrors.ErrInternal, err)
+}
+
+func Test_Resume_should_queue_the_send_without_updating_the_campaign(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: &internalmock.UnitOfWorkMock{}}
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignStarted, nil)
+	outboxMock.On("Create", mock.Anything).Return(nil)
+
+	err := queuedService.Resume(context.Background(), campaignStarted.ID)
+
+	assert.Nil(err)
+	outboxMock.AssertExpectations(t)
+	repositoryMock.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func Test_Schedule_returnErrSendQueued_when_the_send_is_already_queued(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	queueMock := new(internalmock.JobQueueMock)
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock, Jobs: queueMock}
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	queueMock.On("Enqueue", mock.Anything).Return(job.ErrAlreadyQueued)
+
+	err := queuedService.Schedule(context.Background(), campaignPedenting.ID, time.Now().Add(time.Hour))
+
+	assert.Equal(campaign.ErrSendQueued, err)
+}
+
+func Test_Schedule_returnErrSchedulingUnavailable_without_the_job_queue(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+
+	err := service.Schedule(context.Background(), campaignPedenting.ID, time.Now().Add(time.Hour))
+
+	assert.Equal(campaign.ErrSchedulingUnavailable, err)
+}
+
+func Test_Schedule_should_queue_the_send_at_the_given_time(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	queueMock := new(internalmock.JobQueueMock)
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock, Jobs: queueMock}
+	at := time.Now().Add(time.Hour)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	queueMock.On("Enqueue", mock.MatchedBy(func(sendJob *job.Job) bool {
+		return sendJob.RunAt.Equal(at)
+	})).Return(nil)
+
+	err := queuedService.Schedule(context.Background(), campaignPedenting.ID, at)
+
+	assert.Nil(err)
+	queueMock.AssertExpectations(t)
+}
+
+func sendJobFor(t *testing.T, id string, attempts int, maxAttempts int) *job.Job {
+	sendJob, err := job.New(campaign.SendJob, campaign.SendJob+":"+id, map[string]string{"campaign_id": id}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendJob.Attempts = attempts
+	sendJob.MaxAttempts = maxAttempts
+	return sendJob
+}
+
+func Test_HandleSendJob_should_send_a_pending_campaign(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil)
+
+	err := service.HandleSendJob(context.Background(), sendJobFor(t, campaignPedenting.ID, 1, 5))
+
+	assert.Nil(err)
 	assert.Equal(campaign.Done, campaignPedenting.Status)
+}
+
+func Test_HandleSendJob_should_write_the_started_and_finished_events(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	outboxMock := new(internalmock.OutboxRepositoryMock)
+	queuedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock,
+		Outbox: outboxMock, Transactions: &internalmock.UnitOfWorkMock{}}
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(nil)
+	var events []string
+	outboxMock.On("Create", mock.MatchedBy(func(message *outbox.Message) bool {
+		var event webhook.Event
+		json.Unmarshal([]byte(message.Payload), &event)
+		events = append(events, event.Type)
+		return message.Topic == webhook.EventJob
+	})).Return(nil)
+
+	err := queuedService.HandleSendJob(context.Background(), sendJobFor(t, campaignPedenting.ID, 1, 5))
+
+	assert.Nil(err)
+	assert.Equal([]string{webhook.CampaignStarted, webhook.CampaignFinished}, events)
+}
+
+func Test_HandleSendJob_should_keep_the_campaign_started_when_it_is_retried(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	errSend := errors.New("error to send mail")
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	mailerMock.On("Send", mock.Anything).Return(errSend)
+
+	err := service.HandleSendJob(context.Background(), sendJobFor(t, campaignPedenting.ID, 1, 5))
+
+	assert.ErrorIs(err, errSend)
+	assert.Equal(campaign.Started, campaignPedenting.Status)
+}
+
+func Test_HandleSendJob_should_go_back_to_pending_on_the_last_attempt(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignStarted, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	campaignStarted.Contacts = []campaign.Contact{{ID: "c1", Email: "um@test.com", Status: campaign.ContactPending}}
+	mailerMock.On("Send", mock.Anything).Return(errors.New("error to send mail"))
+
+	err := service.HandleSendJob(context.Background(), sendJobFor(t, campaignStarted.ID, 5, 5))
+
+	assert.Equal(internalerrors.ErrInternal, err)
+	assert.Equal(campaign.Peding, campaignStarted.Status)
+}
+
+func Test_HandleSendJob_should_not_retry_a_send_refused_permanently(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignStarted, nil)
+	repositoryMock.On("Update", mock.Anything).Return(nil)
+	repositoryMock.On("UpdateContact", mock.Anything).Return(nil)
+	campaignStarted.Contacts = []campaign.Contact{{ID: "c1", Email: "um@test.com", Status: campaign.ContactPending}}
+	mailerMock.On("Send", mock.Anything).Return(fmt.Errorf("%w: unexpected status 401", campaign.ErrPermanent))
+
+	err := service.HandleSendJob(context.Background(), sendJobFor(t, campaignStarted.ID, 1, 5))
+
+	assert.Nil(err)
+	assert.Equal(campaign.Peding, campaignStarted.Status)
+	assert.Equal(campaign.ContactPending, campaignStarted.Contacts[0].Status)
+}
+
+func Test_HandleSendJob_should_do_nothing_when_the_campaign_is_gone(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	err := service.HandleSendJob(context.Background(), sendJobFor(t, "gone", 1, 5))
+
+	assert.Nil(err)
+	mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+func Test_HandleSendJob_should_release_the_job_after_Shutdown(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	stoppedService := &campaign.ServiceImp{Repository: repositoryMock, Mailer: mailerMock}
+	stoppedService.Shutdown(context.Background())
+
+	err := stoppedService.HandleSendJob(context.Background(), sendJobFor(t, campaignPedenting.ID, 1, 5))
+
+	assert.ErrorIs(err, job.ErrReleased)
+}
+
+func Test_Start_returnErrPreconditionFailed_when_the_version_is_not_expected(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	ctx := campaign.ExpectVersions(context.Background(), []int{campaignPedenting.Version + 1})
+
+	err := service.Start(ctx, campaignPedenting.ID)
+
+	assert.Equal(campaign.ErrPreconditionFailed, err)
+	repositoryMock.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func Test_Start_returnErrVersionConflict_when_the_campaign_changed_meanwhile(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("GetBy", mock.Anything).Return(campaignPedenting, nil)
+	repositoryMock.On("GetSuppressedEmails", mock.Anything).Return([]string{}, nil)
+	repositoryMock.On("Update", mock.Anything).Return(campaign.ErrVersionConflict)
+	ctx := campaign.ExpectVersions(context.Background(), []int{campaignPedenting.Version})
+
+	err := service.Start(ctx, campaignPedenting.ID)
 
+	assert.Equal(campaign.ErrVersionConflict, err)
+	mailerMock.AssertNotCalled(t, "Send", mock.Anything)
 }
@@ -0,0 +1,37 @@
+package campaign
+
+import "time"
+
+// Stats holds the sending totals of a campaign. The counters are aggregated by
+// the repository so the contacts never need to be loaded into memory.
+type Stats struct {
+	CampaignID   string
+	Status       string
+	StartedOn    *time.Time
+	FinishedOn   *time.Time
+	Total        int64
+	Sent         int64
+	Failed       int64
+	Bounced      int64
+	Suppressed   int64
+	Opened       int64
+	Clicked      int64
+	Unsubscribed int64
+}
+
+// Throughput returns the messages sent per second since the campaign started.
+// While the campaign is still sending, now is used as the end of the window.
+func (s *Stats) Throughput(now time.Time) float64 {
+	if s.StartedOn == nil || s.Sent == 0 {
+		return 0
+	}
+	end := now
+	if s.FinishedOn != nil {
+		end = *s.FinishedOn
+	}
+	seconds := end.Sub(*s.StartedOn).Seconds()
+	if seconds <= 0 {
+		return float64(s.Sent)
+	}
+	return float64(s.Sent) / seconds
+}
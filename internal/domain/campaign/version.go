@@ -0,0 +1,43 @@
+package campaign
+
+import (
+	"context"
+	internalerrors "emailn/internal/internal-errors"
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrVersionConflict is returned when the campaign changed between the
+	// read and the update of an operation, like two starts at the same time.
+	ErrVersionConflict = errors.New("the campaign was changed by another request")
+	// ErrPreconditionFailed is returned when the campaign is not at a version
+	// the request expects, see ExpectVersions.
+	ErrPreconditionFailed = errors.New("the campaign is not at the expected version")
+)
+
+type expectedVersionsKey struct{}
+
+// ExpectVersions makes the operations changing a campaign with ctx fail with
+// ErrPreconditionFailed unless the campaign is at one of the versions, the
+// ETags of an If-Match.
+func ExpectVersions(ctx context.Context, versions []int) context.Context {
+	return context.WithValue(ctx, expectedVersionsKey{}, versions)
+}
+
+func checkVersion(ctx context.Context, campaign *Campaign) error {
+	versions, ok := ctx.Value(expectedVersionsKey{}).([]int)
+	if ok && !slices.Contains(versions, campaign.Version) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// updateError is what an operation returns when saving the campaign failed: a
+// version conflict is for the client to solve, anything else is internal.
+func updateError(err error) error {
+	if errors.Is(err, ErrVersionConflict) {
+		return ErrVersionConflict
+	}
+	return internalerrors.ErrInternal
+}
@@ -0,0 +1,33 @@
+package idempotency
+
+import "time"
+
+// Record remembers the response given to a request carrying an
+// Idempotency-Key, so a retry of the same request gets the same response.
+// A record without StatusCode belongs to a request still being processed.
+type Record struct {
+	Key         string `gorm:"size:255;primaryKey"`
+	CreatedBy   string `gorm:"size:50;primaryKey"`
+	RequestHash string `gorm:"size:64"`
+	StatusCode  int
+	Response    string `gorm:"type:text"`
+	CreatedOn   time.Time
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+func (r *Record) Completed() bool {
+	return r.StatusCode != 0
+}
+
+func (r *Record) Expired(window time.Duration, now time.Time) bool {
+	return r.CreatedOn.Add(window).Before(now)
+}
+
+// Stale tells a request in progress for longer than timeout, whose process
+// most likely stopped before completing or aborting it. 0 never times out.
+func (r *Record) Stale(timeout time.Duration, now time.Time) bool {
+	return timeout > 0 && !r.Completed() && r.CreatedOn.Add(timeout).Before(now)
+}
@@ -0,0 +1,16 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	GetBy(ctx context.Context, key string, createdBy string) (*Record, error)
+	Create(ctx context.Context, record *Record) error
+	Update(ctx context.Context, record *Record) error
+	Delete(ctx context.Context, record *Record) error
+	// Purge deletes the records created before createdBefore and returns
+	// how many there were.
+	Purge(ctx context.Context, createdBefore time.Time) (int64, error)
+}
@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"context"
+	internalerrors "emailn/internal/internal-errors"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrKeyReused  = errors.New("idempotency key already used with a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+type Service interface {
+	Begin(ctx context.Context, key string, createdBy string, requestHash string) (*Record, error)
+	Complete(ctx context.Context, record *Record, statusCode int, response string) error
+	Abort(ctx context.Context, record *Record) error
+}
+
+type ServiceImp struct {
+	Repository Repository
+	// Window is how long a key is remembered.
+	Window time.Duration
+	// InProgressTimeout is how long a request may hold its key before a
+	// retry can take it over, 0 holds it for the whole Window.
+	InProgressTimeout time.Duration
+}
+
+// Begin reserves the key for the request. When the same user already made
+// the same request with the key, the completed record is returned and the
+// request must not be processed again; otherwise the record returned is new
+// and must be completed or aborted once the request is processed. A key
+// left in progress past the InProgressTimeout is taken over.
+func (s *ServiceImp) Begin(ctx context.Context, key string, createdBy string, requestHash string) (*Record, error) {
+	record := &Record{Key: key, CreatedBy: createdBy, RequestHash: requestHash, CreatedOn: time.Now()}
+	err := s.Repository.Create(ctx, record)
+	if err == nil {
+		return record, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, internalerrors.ErrInternal
+	}
+
+	saved, err := s.Repository.GetBy(ctx, key, createdBy)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// aborted in the meantime
+		return s.Begin(ctx, key, createdBy, requestHash)
+	}
+	if err != nil {
+		return nil, internalerrors.ErrInternal
+	}
+
+	now := time.Now()
+	if saved.Expired(s.Window, now) || saved.Stale(s.InProgressTimeout, now) {
+		if err := s.Repository.Delete(ctx, saved); err != nil {
+			return nil, internalerrors.ErrInternal
+		}
+		return s.Begin(ctx, key, createdBy, requestHash)
+	}
+	if saved.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	if !saved.Completed() {
+		return nil, ErrInProgress
+	}
+	return saved, nil
+}
+
+// Complete and Abort run after the request was processed, they are not
+// cancelled when the client goes away so the key is never left in progress.
+func (s *ServiceImp) Complete(ctx context.Context, record *Record, statusCode int, response string) error {
+	record.StatusCode = statusCode
+	record.Response = response
+	if err := s.Repository.Update(context.WithoutCancel(ctx), record); err != nil {
+		return internalerrors.ErrInternal
+	}
+	return nil
+}
+
+// Purge deletes the records older than the Window, Begin no longer uses them.
+func (s *ServiceImp) Purge(ctx context.Context) (int64, error) {
+	purged, err := s.Repository.Purge(ctx, time.Now().Add(-s.Window))
+	if err != nil {
+		return 0, internalerrors.ErrInternal
+	}
+	return purged, nil
+}
+
+// Abort forgets the key of a request that failed, so it can be retried.
+func (s *ServiceImp) Abort(ctx context.Context, record *Record) error {
+	if err := s.Repository.Delete(context.WithoutCancel(ctx), record); err != nil {
+		return internalerrors.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package idempotency_test
+
+import (
+	"context"
+	"emailn/internal/domain/idempotency"
+	internalerrors "emailn/internal/internal-errors"
+	internalmock "emailn/internal/test/internal-mock"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+var (
+	repositoryMock *internalmock.IdempotencyRepositoryMock
+	service        *idempotency.ServiceImp
+)
+
+func setUp() {
+	repositoryMock = new(internalmock.IdempotencyRepositoryMock)
+	service = &idempotency.ServiceImp{Repository: repositoryMock, Window: time.Hour}
+}
+
+func Test_Begin_returnNewRecord_when_key_is_new(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("Create", mock.Anything).Return(nil)
+
+	record, err := service.Begin(context.Background(), "key1", "teste@test.com", "hash")
+
+	assert.Nil(err)
+	assert.False(record.Completed())
+	assert.Equal("key1", record.Key)
+	assert.Equal("teste@test.com", record.CreatedBy)
+}
+
+func Test_Begin_returnCompletedRecord_when_same_request_is_repeated(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	saved := &idempotency.Record{Key: "key1", RequestHash: "hash", StatusCode: 201, Response: `{"id":"1"}`, CreatedOn: time.Now()}
+	repositoryMock.On("Create", mock.Anything).Return(gorm.ErrDuplicatedKey)
+	repositoryMock.On("GetBy", "key1", "teste@test.com").Return(saved, nil)
+
+	record, err := service.Begin(context.Background(), "key1", "teste@test.com", "hash")
+
+	assert.Nil(err)
+	assert.Equal(saved, record)
+}
+
+func Test_Begin_returnErrKeyReused_when_request_differs(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	saved := &idempotency.Record{Key: "key1", RequestHash: "hash", StatusCode: 201, CreatedOn: time.Now()}
+	repositoryMock.On("Create", mock.Anything).Return(gorm.ErrDuplicatedKey)
+	repositoryMock.On("GetBy", mock.Anything, mock.Anything).Return(saved, nil)
+
+	_, err := service.Begin(context.Background(), "key1", "teste@test.com", "other hash")
+
+	assert.Equal(idempotency.ErrKeyReused, err)
+}
+
+func Test_Begin_returnErrInProgress_when_first_request_is_not_completed(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	saved := &idempotency.Record{Key: "key1", RequestHash: "hash", CreatedOn: time.Now()}
+	repositoryMock.On("Create", mock.Anything).Return(gorm.ErrDuplicatedKey)
+	repositoryMock.On("GetBy", mock.Anything, mock.Anything).Return(saved, nil)
+
+	_, err := service.Begin(context.Background(), "key1", "teste@test.com", "hash")
+
+	assert.Equal(idempotency.ErrInProgress, err)
+}
+
+func Test_Begin_should_reuse_expired_key(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	expired := &idempotency.Record{Key: "key1", RequestHash: "old hash", StatusCode: 201, CreatedOn: time.Now().Add(-2 * time.Hour)}
+	repositoryMock.On("Create", mock.Anything).Return(gorm.ErrDuplicatedKey).Once()
+	repositoryMock.On("GetBy", mock.Anything, mock.Anything).Return(expired, nil)
+	repositoryMock.On("Delete", expired).Return(nil)
+	repositoryMock.On("Create", mock.Anything).Return(nil)
+
+	record, err := service.Begin(context.Background(), "key1", "teste@test.com", "hash")
+
+	assert.Nil(err)
+	assert.Equal("hash", record.RequestHash)
+	repositoryMock.AssertCalled(t, "Delete", expired)
+}
+
+func Test_Begin_should_take_over_a_key_in_progress_past_the_timeout(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	service.InProgressTimeout = time.Minute
+	stale := &idempotency.Record{Key: "key1", RequestHash: "hash", CreatedOn: time.Now().Add(-2 * time.Minute)}
+	repositoryMock.On("Create", mock.Anything).Return(gorm.ErrDuplicatedKey).Once()
+	repositoryMock.On("GetBy", mock.Anything, mock.Anything).Return(stale, nil)
+	repositoryMock.On("Delete", stale).Return(nil)
+	repositoryMock.On("Create", mock.Anything).Return(nil)
+
+	record, err := service.Begin(context.Background(), "key1", "teste@test.com", "hash")
+
+	assert.Nil(err)
+	assert.False(record.Completed())
+	repositoryMock.AssertCalled(t, "Delete", stale)
+}
+
+func Test_Purge_should_delete_the_records_older_than_the_window(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("Purge", mock.MatchedBy(func(createdBefore time.Time) bool {
+		return time.Since(createdBefore) >= time.Hour
+	})).Return(int64(3), nil)
+
+	purged, err := service.Purge(context.Background())
+
+	assert.Nil(err)
+	assert.Equal(int64(3), purged)
+}
+
+func Test_Begin_returnInternalError_when_repository_fails(t *testing.T) {
+	setUp()
+	assert := assert.New(t)
+	repositoryMock.On("Create", mock.Anything).Return(errors.New("connection refused"))
+
+	_, err := service.Begin(context.Background(), "key1", "teste@test.com", "hash")
+
+	assert.Equal(internalerrors.ErrInternal, err)
+}
@@ -0,0 +1,89 @@
+package job
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	Queued  = "Queued"
+	Running = "Running"
+	Done    = "Done"
+	Dead    = "Dead"
+)
+
+// Job is a unit of work kept in the database until a worker finished it, so
+// it survives a crash of the process running it. A Running job whose lease
+// expired is taken over by the next worker polling.
+type Job struct {
+	ID   string `gorm:"size:50"`
+	Kind string `gorm:"size:50"`
+	// Key, when set, lets only one Queued or Running job exist for it.
+	Key         string `gorm:"size:255"`
+	Payload     string `gorm:"type:text"`
+	Status      string `gorm:"size:20"`
+	Attempts    int
+	MaxAttempts int
+	// RunAt is the earliest time the job may run.
+	RunAt time.Time
+	// LockedBy is the worker holding the lease until LockedUntil.
+	LockedBy    string `gorm:"size:100"`
+	LockedUntil *time.Time
+	LastError   string `gorm:"type:text"`
+	CreatedOn   time.Time
+	FinishedOn  *time.Time
+}
+
+func New(kind string, key string, payload any, runAt time.Time) (*Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{
+		ID:        xid.New().String(),
+		Kind:      kind,
+		Key:       key,
+		Payload:   string(encoded),
+		Status:    Queued,
+		RunAt:     runAt.UTC(),
+		CreatedOn: time.Now().UTC(),
+	}, nil
+}
+
+func (j *Job) Decode(payload any) error {
+	return json.Unmarshal([]byte(j.Payload), payload)
+}
+
+// LastAttempt tells the handler a failure now is final.
+func (j *Job) LastAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
+
+// Retry queues the job again to run at the given time.
+func (j *Job) Retry(at time.Time, err error) {
+	j.Status = Queued
+	j.RunAt = at.UTC()
+	j.LastError = err.Error()
+}
+
+// Release queues the job again without counting the attempt.
+func (j *Job) Release(err error) {
+	j.Attempts--
+	j.Retry(time.Now(), err)
+}
+
+// Kill moves the job to the dead letters, where it stays for inspection.
+func (j *Job) Kill(err error) {
+	now := time.Now().UTC()
+	j.Status = Dead
+	j.LastError = err.Error()
+	j.FinishedOn = &now
+}
+
+func (j *Job) Done() {
+	now := time.Now().UTC()
+	j.Status = Done
+	j.FinishedOn = &now
+}
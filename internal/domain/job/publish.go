@@ -0,0 +1,25 @@
+package job
+
+import (
+	"context"
+	"emailn/internal/domain/outbox"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Publish makes the outbox message a job of the kind of its Topic, to run
+// now, see outbox.Relay. A job already queued with the Key of the message
+// does the work it asks for, the message is then dropped.
+func (q *Queue) Publish(ctx context.Context, message *outbox.Message) error {
+	published, err := New(message.Topic, message.Key, json.RawMessage(message.Payload), time.Now())
+	if err != nil {
+		return err
+	}
+	err = q.Enqueue(ctx, published)
+	if errors.Is(err, ErrAlreadyQueued) {
+		q.logger().Info("job of outbox message already queued", "message_id", message.ID, "key", message.Key)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyQueued is returned by Enqueue when a Queued or Running job has
+// the Key of the new one.
+var ErrAlreadyQueued = errors.New("a job with this key is already queued")
+
+// ErrReleased is wrapped by a handler that stopped before finishing, like on
+// shutdown. The job runs again right away without counting the attempt.
+var ErrReleased = errors.New("job released")
+
+// maxBackoff caps the wait between the attempts of a job.
+const maxBackoff = time.Hour
+
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue runs the jobs of the kinds it has handlers for. Many processes can
+// work the same Repository, each job is leased to a single worker at a time.
+type Queue struct {
+	Repository Repository
+	// Workers is how many jobs run at the same time, defaults to 1.
+	Workers      int
+	PollInterval time.Duration
+	// Visibility is how long a job stays leased to a worker that stopped
+	// renewing it before another worker may take it over.
+	Visibility time.Duration
+	// MaxAttempts before a job is moved to the dead letters, defaults to 1.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after
+	// every other one.
+	Backoff time.Duration
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+
+	once     sync.Once
+	worker   string
+	handlers map[string]Handler
+}
+
+func (q *Queue) Handle(kind string, handler Handler) {
+	if q.handlers == nil {
+		q.handlers = map[string]Handler{}
+	}
+	q.handlers[kind] = handler
+}
+
+// Enqueue stores the job to run at its RunAt.
+func (q *Queue) Enqueue(ctx context.Context, job *Job) error {
+	job.MaxAttempts = max(q.MaxAttempts, 1)
+	err := q.Repository.Create(ctx, job)
+	if errors.Is(err, gorm.ErrDuplicatedKey) && job.Key != "" {
+		return ErrAlreadyQueued
+	}
+	return err
+}
+
+// Run works the queue with Workers goroutines until ctx is done. The jobs
+// running then are not cancelled, their handlers are expected to stop on
+// their own shutdown and return ErrReleased.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < max(q.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.poll(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) poll(ctx context.Context) {
+	for {
+		found, err := q.Work(ctx)
+		if err != nil {
+			q.logger().Error("working job queue", "error", err)
+		}
+		if found && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// Work runs the next job due, if any, and tells whether there was one.
+func (q *Queue) Work(ctx context.Context) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	now := time.Now().UTC()
+	job, err := q.Repository.Claim(ctx, kinds, q.workerID(), now, now.Add(q.Visibility))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// the job outlives the poll, only its handler decides when to stop, unless
+	// the lease is lost and another worker runs the job
+	finishCtx := context.WithoutCancel(ctx)
+	runCtx, cancelRun := context.WithCancelCause(finishCtx)
+	defer cancelRun(nil)
+	logger := q.logger().With("job_id", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+	stopRenewing := q.renew(runCtx, job, logger, cancelRun)
+	err = q.run(runCtx, job)
+	stopRenewing()
+
+	if errors.Is(context.Cause(runCtx), ErrLeaseLost) {
+		logger.Warn("job lease lost, its handler was stopped", "error", err)
+		return true, nil
+	}
+	switch {
+	case err == nil:
+		job.Done()
+	case errors.Is(err, ErrReleased):
+		logger.Info("job released", "error", err)
+		job.Release(err)
+	case job.LastAttempt():
+		logger.Error("job failed for the last time, moved to the dead letters", "error", err)
+		job.Kill(err)
+	default:
+		at := time.Now().Add(q.backoff(job.Attempts))
+		logger.Warn("job failed, retrying", "error", err, "retry_at", at)
+		job.Retry(at, err)
+	}
+	return true, q.Repository.Finish(finishCtx, job)
+}
+
+func (q *Queue) run(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return q.handlers[job.Kind](ctx, job)
+}
+
+// renew extends the lease while the handler runs, so a long job is not taken
+// over by another worker. When the lease is lost anyway, lost cancels the
+// handler with ErrLeaseLost.
+func (q *Queue) renew(ctx context.Context, job *Job, logger *slog.Logger, lost context.CancelCauseFunc) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	lease := *job
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(max(q.Visibility/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := q.Repository.Extend(ctx, &lease, time.Now().Add(q.Visibility))
+				if errors.Is(err, ErrLeaseLost) {
+					lost(ErrLeaseLost)
+					return
+				}
+				if err != nil && ctx.Err() == nil {
+					logger.Warn("renewing job lease", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	backoff := q.Backoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func (q *Queue) workerID() string {
+	q.once.Do(func() {
+		hostname, _ := os.Hostname()
+		q.worker = fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), xid.New().String())
+	})
+	return q.worker
+}
+
+func (q *Queue) logger() *slog.Logger {
+	if q.Logger == nil {
+		return slog.Default()
+	}
+	return q.Logger
+}
@@ -0,0 +1,226 @@
+package job_test
+
+import (
+	"context"
+	"emailn/internal/domain/job"
+	"emailn/internal/infrastructure/memory"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const testJob = "test.job"
+
+func newQueue(handler job.Handler) (*job.Queue, *memory.JobRepository) {
+	repository := memory.NewJobRepository()
+	queue := &job.Queue{
+		Repository:   repository,
+		PollInterval: time.Millisecond,
+		Visibility:   time.Minute,
+		MaxAttempts:  3,
+		Backoff:      time.Minute,
+	}
+	queue.Handle(testJob, handler)
+	return queue, repository
+}
+
+func enqueue(t *testing.T, queue *job.Queue, key string) *job.Job {
+	queued, err := job.New(testJob, key, map[string]string{"key": key}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Enqueue(context.Background(), queued); err != nil {
+		t.Fatal(err)
+	}
+	return queued
+}
+
+func Test_Work_should_remove_the_job_when_done(t *testing.T) {
+	assert := assert.New(t)
+	var payload map[string]string
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		return running.Decode(&payload)
+	})
+	queued := enqueue(t, queue, "a")
+
+	found, err := queue.Work(context.Background())
+
+	assert.True(found)
+	assert.Nil(err)
+	assert.Equal(map[string]string{"key": "a"}, payload)
+	_, err = repository.GetBy(context.Background(), queued.ID)
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func Test_Work_should_return_false_when_no_job_is_due(t *testing.T) {
+	assert := assert.New(t)
+	queue, _ := newQueue(func(ctx context.Context, running *job.Job) error { return nil })
+	later, _ := job.New(testJob, "", nil, time.Now().Add(time.Hour))
+	queue.Enqueue(context.Background(), later)
+
+	found, err := queue.Work(context.Background())
+
+	assert.False(found)
+	assert.Nil(err)
+}
+
+func Test_Work_should_retry_the_job_with_backoff(t *testing.T) {
+	assert := assert.New(t)
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		return errors.New("smtp down")
+	})
+	queued := enqueue(t, queue, "a")
+
+	queue.Work(context.Background())
+
+	saved, _ := repository.GetBy(context.Background(), queued.ID)
+	assert.Equal(job.Queued, saved.Status)
+	assert.Equal(1, saved.Attempts)
+	assert.Equal("smtp down", saved.LastError)
+	assert.WithinDuration(time.Now().Add(time.Minute), saved.RunAt, 5*time.Second)
+	assert.Nil(saved.LockedUntil)
+}
+
+func Test_Work_should_move_the_job_to_the_dead_letters_after_MaxAttempts(t *testing.T) {
+	assert := assert.New(t)
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		return errors.New("smtp down")
+	})
+	queue.Backoff = 0
+	queued := enqueue(t, queue, "a")
+
+	for i := 0; i < 3; i++ {
+		queue.Work(context.Background())
+	}
+	found, _ := queue.Work(context.Background())
+
+	saved, _ := repository.GetBy(context.Background(), queued.ID)
+	assert.False(found)
+	assert.Equal(job.Dead, saved.Status)
+	assert.Equal(3, saved.Attempts)
+	assert.NotNil(saved.FinishedOn)
+}
+
+func Test_Work_should_not_count_the_attempt_of_a_released_job(t *testing.T) {
+	assert := assert.New(t)
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		return fmt.Errorf("%w: shutting down", job.ErrReleased)
+	})
+	queued := enqueue(t, queue, "a")
+
+	queue.Work(context.Background())
+
+	saved, _ := repository.GetBy(context.Background(), queued.ID)
+	assert.Equal(job.Queued, saved.Status)
+	assert.Equal(0, saved.Attempts)
+	assert.False(saved.RunAt.After(time.Now()))
+}
+
+func Test_Work_should_retry_the_job_when_its_handler_panics(t *testing.T) {
+	assert := assert.New(t)
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		panic("boom")
+	})
+	queued := enqueue(t, queue, "a")
+
+	found, err := queue.Work(context.Background())
+
+	saved, _ := repository.GetBy(context.Background(), queued.ID)
+	assert.True(found)
+	assert.Nil(err)
+	assert.Equal(job.Queued, saved.Status)
+	assert.Contains(saved.LastError, "boom")
+}
+
+func Test_Work_should_renew_the_lease_while_the_job_runs(t *testing.T) {
+	assert := assert.New(t)
+	var lockedUntil []time.Time
+	var repository *memory.JobRepository
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		for i := 0; i < 2; i++ {
+			time.Sleep(40 * time.Millisecond)
+			saved, _ := repository.GetBy(ctx, running.ID)
+			lockedUntil = append(lockedUntil, *saved.LockedUntil)
+		}
+		return nil
+	})
+	queue.Visibility = 30 * time.Millisecond
+	enqueue(t, queue, "a")
+
+	queue.Work(context.Background())
+
+	assert.Len(lockedUntil, 2)
+	assert.True(lockedUntil[1].After(lockedUntil[0]))
+}
+
+func Test_Work_should_stop_the_handler_when_the_lease_is_lost(t *testing.T) {
+	assert := assert.New(t)
+	var repository *memory.JobRepository
+	var handlerErr error
+	queue, repository := newQueue(func(ctx context.Context, running *job.Job) error {
+		// another worker takes the job over, as if the lease had expired
+		later := time.Now().Add(time.Hour)
+		repository.Claim(context.Background(), []string{testJob}, "other", later, later.Add(time.Minute))
+		select {
+		case <-ctx.Done():
+			handlerErr = context.Cause(ctx)
+		case <-time.After(time.Second):
+		}
+		return handlerErr
+	})
+	queue.Visibility = 30 * time.Millisecond
+	queued := enqueue(t, queue, "a")
+
+	found, err := queue.Work(context.Background())
+
+	assert.True(found)
+	assert.Nil(err)
+	assert.ErrorIs(handlerErr, job.ErrLeaseLost)
+	saved, _ := repository.GetBy(context.Background(), queued.ID)
+	assert.Equal(job.Running, saved.Status)
+	assert.Equal("other", saved.LockedBy)
+}
+
+func Test_Enqueue_returnErrAlreadyQueued_when_the_key_is_queued(t *testing.T) {
+	assert := assert.New(t)
+	queue, _ := newQueue(func(ctx context.Context, running *job.Job) error { return nil })
+	enqueue(t, queue, "a")
+	again, _ := job.New(testJob, "a", nil, time.Now())
+
+	err := queue.Enqueue(context.Background(), again)
+
+	assert.Equal(job.ErrAlreadyQueued, err)
+}
+
+func Test_Run_should_work_the_jobs_until_cancelled(t *testing.T) {
+	assert := assert.New(t)
+	var ran atomic.Int32
+	queue, _ := newQueue(func(ctx context.Context, running *job.Job) error {
+		ran.Add(1)
+		return nil
+	})
+	queue.Workers = 2
+	for _, key := range []string{"a", "b", "c"} {
+		enqueue(t, queue, key)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		queue.Run(ctx)
+	}()
+	assert.Eventually(func() bool { return ran.Load() == 3 }, time.Second, time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
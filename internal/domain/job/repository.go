@@ -0,0 +1,27 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLeaseLost is returned when the lease of the worker expired and another
+// worker took the job over.
+var ErrLeaseLost = errors.New("job lease lost")
+
+type Repository interface {
+	// Create returns gorm.ErrDuplicatedKey when a Queued or Running job
+	// already has the Key.
+	Create(ctx context.Context, job *Job) error
+	GetBy(ctx context.Context, id string) (*Job, error)
+	// Claim leases to worker, until the given time, the oldest job of the
+	// kinds that is due at now and not leased, counting an attempt. It returns
+	// gorm.ErrRecordNotFound when there is none.
+	Claim(ctx context.Context, kinds []string, worker string, now time.Time, until time.Time) (*Job, error)
+	// Extend pushes the lease of the worker named in LockedBy to until.
+	Extend(ctx context.Context, job *Job, until time.Time) error
+	// Finish saves the outcome of the job and releases the lease of the
+	// worker named in LockedBy. Done jobs are removed.
+	Finish(ctx context.Context, job *Job) error
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	Pending = "Pending"
+	Dead    = "Dead"
+)
+
+// Message is something to publish once the transaction writing it commits,
+// like the job sending a campaign whose status changed in that transaction.
+type Message struct {
+	ID    string `gorm:"size:50"`
+	Topic string `gorm:"size:50"`
+	// Key identifies what the message is about, the job queue dedupes on it.
+	Key     string `gorm:"size:255"`
+	Payload string `gorm:"type:text"`
+	Status  string `gorm:"size:20"`
+	// Attempts counts the failed publishes.
+	Attempts int
+	// NextAttemptAt is the earliest time the message may be published.
+	NextAttemptAt time.Time
+	LastError     string `gorm:"type:text"`
+	CreatedOn     time.Time
+}
+
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+func New(topic string, key string, payload any) (*Message, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return &Message{
+		ID:            xid.New().String(),
+		Topic:         topic,
+		Key:           key,
+		Payload:       string(encoded),
+		Status:        Pending,
+		NextAttemptAt: now,
+		CreatedOn:     now,
+	}, nil
+}
+
+// Retry counts the failed publish and holds the message until the given time.
+func (m *Message) Retry(at time.Time, err error) {
+	m.Attempts++
+	m.NextAttemptAt = at.UTC()
+	m.LastError = err.Error()
+}
+
+// Kill counts the failed publish and moves the message to the dead letters,
+// where it stays for inspection and is not published again.
+func (m *Message) Kill(err error) {
+	m.Attempts++
+	m.Status = Dead
+	m.LastError = err.Error()
+}
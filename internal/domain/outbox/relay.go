@@ -0,0 +1,111 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, message *Message) error
+}
+
+// maxBackoff caps the wait between the attempts of a message.
+const maxBackoff = time.Hour
+
+// Relay publishes the messages of the outbox, oldest first. A message is
+// published and removed in one transaction: a Publisher writing in the same
+// database, like the job queue, gets every message exactly once, any other
+// at least once. A message failing to publish waits Backoff, doubled after
+// every attempt, while the next ones are published, and after MaxAttempts
+// it is moved to the dead letters.
+type Relay struct {
+	Repository   Repository
+	Transactions UnitOfWork
+	Publisher    Publisher
+	PollInterval time.Duration
+	// MaxAttempts before a message is moved to the dead letters, defaults
+	// to 1.
+	MaxAttempts int
+	Backoff     time.Duration
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Run relays the messages until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		found, err := r.RelayNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger().Error("relaying outbox message", "error", err)
+		}
+		if found && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
+
+// RelayNext publishes the oldest message due, if any, and tells whether
+// there was one. A message failing to publish stays in the outbox for a
+// later attempt, or as a dead letter after the last one.
+func (r *Relay) RelayNext(ctx context.Context) (bool, error) {
+	found := false
+	var failed *Message
+	var errPublish error
+	err := r.Transactions.Do(ctx, func(ctx context.Context) error {
+		message, err := r.Repository.Next(ctx, time.Now().UTC())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if err := r.Publisher.Publish(ctx, message); err != nil {
+			failed, errPublish = message, err
+			return err
+		}
+		return r.Repository.Delete(ctx, message)
+	})
+	if failed != nil {
+		// saved after the rollback, which the failed publish may have forced
+		return found, errors.Join(errPublish, r.fail(context.WithoutCancel(ctx), failed, errPublish))
+	}
+	return found, err
+}
+
+func (r *Relay) fail(ctx context.Context, message *Message, err error) error {
+	logger := r.logger().With("message_id", message.ID, "topic", message.Topic, "key", message.Key)
+	if message.Attempts+1 >= r.MaxAttempts {
+		logger.Error("outbox message failed for the last time, moved to the dead letters", "error", err)
+		message.Kill(err)
+	} else {
+		at := time.Now().Add(r.backoff(message.Attempts + 1))
+		logger.Warn("outbox message failed, retrying", "error", err, "retry_at", at)
+		message.Retry(at, err)
+	}
+	return r.Repository.Update(ctx, message)
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.Backoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func (r *Relay) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+	return r.Logger
+}
@@ -0,0 +1,114 @@
+package outbox_test
+
+import (
+	"context"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/infrastructure/memory"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type publisherFunc func(ctx context.Context, message *outbox.Message) error
+
+func (f publisherFunc) Publish(ctx context.Context, message *outbox.Message) error {
+	return f(ctx, message)
+}
+
+func newRelay(publisher publisherFunc) (*outbox.Relay, *memory.OutboxRepository) {
+	repository := memory.NewOutboxRepository()
+	return &outbox.Relay{Repository: repository, Transactions: memory.UnitOfWork{}, Publisher: publisher,
+		MaxAttempts: 2, Backoff: time.Minute}, repository
+}
+
+func Test_RelayNext_should_publish_and_remove_the_message(t *testing.T) {
+	assert := assert.New(t)
+	var published []string
+	relay, repository := newRelay(func(ctx context.Context, message *outbox.Message) error {
+		published = append(published, message.Key)
+		return nil
+	})
+	message, _ := outbox.New("campaign.send", "campaign.send:1", nil)
+	repository.Create(context.Background(), message)
+
+	found, err := relay.RelayNext(context.Background())
+	again, _ := relay.RelayNext(context.Background())
+
+	assert.True(found)
+	assert.Nil(err)
+	assert.False(again)
+	assert.Equal([]string{"campaign.send:1"}, published)
+}
+
+func Test_RelayNext_should_keep_the_message_backing_off_when_publishing_fails(t *testing.T) {
+	assert := assert.New(t)
+	errPublish := errors.New("queue unavailable")
+	relay, repository := newRelay(func(ctx context.Context, message *outbox.Message) error {
+		return errPublish
+	})
+	message, _ := outbox.New("campaign.send", "campaign.send:1", nil)
+	repository.Create(context.Background(), message)
+
+	found, err := relay.RelayNext(context.Background())
+
+	assert.True(found)
+	assert.ErrorIs(err, errPublish)
+	_, err = repository.Next(context.Background(), time.Now())
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	next, _ := repository.Next(context.Background(), time.Now().Add(2*time.Minute))
+	assert.Equal(message.ID, next.ID)
+	assert.Equal(1, next.Attempts)
+	assert.Equal(errPublish.Error(), next.LastError)
+}
+
+func Test_RelayNext_should_publish_the_next_message_while_one_backs_off(t *testing.T) {
+	assert := assert.New(t)
+	var published []string
+	relay, repository := newRelay(func(ctx context.Context, message *outbox.Message) error {
+		if message.Key == "campaign.send:1" {
+			return errors.New("queue unavailable")
+		}
+		published = append(published, message.Key)
+		return nil
+	})
+	failing, _ := outbox.New("campaign.send", "campaign.send:1", nil)
+	next, _ := outbox.New("campaign.send", "campaign.send:2", nil)
+	next.CreatedOn = failing.CreatedOn.Add(time.Second)
+	repository.Create(context.Background(), failing)
+	repository.Create(context.Background(), next)
+
+	relay.RelayNext(context.Background())
+	found, err := relay.RelayNext(context.Background())
+
+	assert.True(found)
+	assert.Nil(err)
+	assert.Equal([]string{"campaign.send:2"}, published)
+}
+
+func Test_RelayNext_should_move_the_message_to_the_dead_letters_after_the_last_attempt(t *testing.T) {
+	assert := assert.New(t)
+	relay, repository := newRelay(func(ctx context.Context, message *outbox.Message) error {
+		return errors.New("queue unavailable")
+	})
+	message, _ := outbox.New("campaign.send", "campaign.send:1", nil)
+	message.Attempts = 1
+	repository.Create(context.Background(), message)
+
+	relay.RelayNext(context.Background())
+
+	_, err := repository.Next(context.Background(), time.Now().Add(24*time.Hour))
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func Test_RelayNext_should_return_false_when_the_outbox_is_empty(t *testing.T) {
+	assert := assert.New(t)
+	relay, _ := newRelay(func(ctx context.Context, message *outbox.Message) error { return nil })
+
+	found, err := relay.RelayNext(context.Background())
+
+	assert.False(found)
+	assert.Nil(err)
+}
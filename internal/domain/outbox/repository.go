@@ -0,0 +1,23 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+// UnitOfWork runs fn in a transaction: the repositories called with the ctx
+// given to fn write in it, and it is rolled back when fn returns an error.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Repository interface {
+	Create(ctx context.Context, message *Message) error
+	// Next returns the oldest Pending message due at now, locked until the
+	// end of the transaction of ctx so no other relay takes it. It returns
+	// gorm.ErrRecordNotFound when there is none.
+	Next(ctx context.Context, now time.Time) (*Message, error)
+	// Update saves the status and the attempts of the message.
+	Update(ctx context.Context, message *Message) error
+	Delete(ctx context.Context, message *Message) error
+}
@@ -0,0 +1,30 @@
+package webhook_test
+
+import (
+	"emailn/internal/domain/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewClient_should_refuse_the_addresses_that_are_not_public(t *testing.T) {
+	client := webhook.NewClient(time.Second)
+	for _, url := range []string{
+		"https://127.0.0.1/hooks",
+		"https://[::1]/hooks",
+		"https://0.0.0.0/hooks",
+		"https://10.1.2.3/hooks",
+		"https://172.16.0.1/hooks",
+		"https://192.168.0.1/hooks",
+		"https://100.64.0.1/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[fd00::1]/hooks",
+		"https://[fe80::1]/hooks",
+		"https://[::ffff:127.0.0.1]/hooks",
+	} {
+		_, err := client.Get(url)
+
+		assert.ErrorIs(t, err, webhook.ErrAddressNotAllowed, url)
+	}
+}
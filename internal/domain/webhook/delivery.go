@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// DeliveryPending is a delivery waiting for its first or next attempt.
+	DeliveryPending   = "Pending"
+	DeliverySucceeded = "Succeeded"
+	// DeliveryFailed is a delivery whose attempts ran out, it can be
+	// replayed.
+	DeliveryFailed = "Failed"
+)
+
+// Delivery is one event posted, or to post, to one subscription. Its log is
+// kept so it can be inspected and replayed.
+type Delivery struct {
+	// ID is made of the event and subscription ids, an event is delivered
+	// once to every subscription.
+	ID             string `gorm:"size:50"`
+	SubscriptionID string `gorm:"size:50;index"`
+	EventID        string `gorm:"size:50"`
+	EventType      string `gorm:"size:50"`
+	Payload        string `gorm:"type:text"`
+	Status         string `gorm:"size:20"`
+	Attempts       int
+	// ResponseStatus is the HTTP status of the last attempt, 0 when it got
+	// no response.
+	ResponseStatus int
+	LastError      string `gorm:"type:text"`
+	CreatedOn      time.Time
+	LastAttemptOn  *time.Time
+	DeliveredOn    *time.Time
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func NewDelivery(subscription *Subscription, event *Event) (*Delivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &Delivery{
+		ID:             event.ID + "-" + subscription.ID,
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Payload:        string(payload),
+		Status:         DeliveryPending,
+		CreatedOn:      time.Now(),
+	}, nil
+}
+
+// Attempted records an attempt answered with status, 0 without an answer.
+// A failed attempt leaves the delivery Pending unless it was the last one.
+func (d *Delivery) Attempted(status int, err error, last bool) {
+	now := time.Now()
+	d.Attempts++
+	d.ResponseStatus = status
+	d.LastAttemptOn = &now
+	switch {
+	case err == nil:
+		d.Status = DeliverySucceeded
+		d.LastError = ""
+		d.DeliveredOn = &now
+	case last:
+		d.Status = DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.LastError = err.Error()
+	}
+}
+
+// Replay puts the delivery back to Pending, to be attempted again.
+func (d *Delivery) Replay() {
+	d.Status = DeliveryPending
+	d.DeliveredOn = nil
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"emailn/internal/domain/job"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The headers of a delivery. The signature is the hex HMAC-SHA256, keyed
+// with the secret of the subscription, of the timestamp, a dot and the body;
+// receivers should refuse old timestamps so a captured delivery cannot be
+// replayed by someone else.
+const (
+	HeaderEvent     = "X-Emailn-Event"
+	HeaderDelivery  = "X-Emailn-Delivery"
+	HeaderTimestamp = "X-Emailn-Timestamp"
+	HeaderSignature = "X-Emailn-Signature"
+)
+
+type deliverPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// Sign returns the HeaderSignature of the body sent at timestamp, in Unix
+// seconds.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// HandleEvent runs an EventJob: it makes a delivery of the event for every
+// subscription of its owner asking for its type. Run again, it only queues
+// the deliveries still pending.
+func (s *ServiceImp) HandleEvent(ctx context.Context, eventJob *job.Job) error {
+	var event Event
+	if err := eventJob.Decode(&event); err != nil {
+		return err
+	}
+	if event.Owner == "" {
+		// GetSubscriptions would return the subscriptions of everybody
+		s.logger(ctx).Warn("webhook event without owner dropped", "event_id", event.ID, "event_type", event.Type)
+		return nil
+	}
+	subscriptions, err := s.Repository.GetSubscriptions(ctx, event.Owner)
+	if err != nil {
+		return err
+	}
+
+	for i := range subscriptions {
+		if !subscriptions[i].Wants(event.Type) {
+			continue
+		}
+		delivery, err := NewDelivery(&subscriptions[i], &event)
+		if err != nil {
+			return err
+		}
+		err = s.Repository.CreateDelivery(ctx, delivery)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			delivery, err = s.Repository.GetDelivery(ctx, delivery.ID)
+			if err == nil && delivery.Status != DeliveryPending {
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.enqueue(ctx, delivery); err != nil && !errors.Is(err, job.ErrAlreadyQueued) {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleDeliver runs a DeliverJob: it posts the delivery to its
+// subscription. A failed attempt returns its error for the job queue to
+// retry, the last one leaves the delivery Failed.
+func (s *ServiceImp) HandleDeliver(ctx context.Context, deliverJob *job.Job) error {
+	var payload deliverPayload
+	if err := deliverJob.Decode(&payload); err != nil {
+		return err
+	}
+	delivery, err := s.Repository.GetDelivery(ctx, payload.DeliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// the subscription was deleted with its deliveries
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != DeliveryPending {
+		return nil
+	}
+	subscription, err := s.Repository.GetSubscription(ctx, delivery.SubscriptionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := s.post(ctx, subscription, delivery)
+	delivery.Attempted(status, err, deliverJob.LastAttempt())
+	logger := s.logger(ctx).With("webhook_id", subscription.ID, "delivery_id", delivery.ID,
+		"event_type", delivery.EventType, "attempt", delivery.Attempts, "response_status", status)
+	switch {
+	case err == nil:
+		logger.Info("webhook delivered")
+	case delivery.Status == DeliveryFailed:
+		logger.Error("webhook delivery failed for the last time", "error", err)
+	default:
+		logger.Warn("webhook delivery failed, retrying", "error", err)
+	}
+
+	if updateErr := s.Repository.UpdateDelivery(context.WithoutCancel(ctx), delivery); updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+	return err
+}
+
+// post sends the delivery, returning the HTTP status of the answer. Any
+// status but 2xx is an error.
+func (s *ServiceImp) post(ctx context.Context, subscription *Subscription, delivery *Delivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "emailn-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	res, err := s.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook endpoint answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+func (s *ServiceImp) enqueue(ctx context.Context, delivery *Delivery) error {
+	deliverJob, err := job.New(DeliverJob, DeliverJob+":"+delivery.ID, deliverPayload{DeliveryID: delivery.ID}, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.Jobs.Enqueue(ctx, deliverJob)
+}
@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"emailn/internal/domain/outbox"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// EventJob is the job dispatching an event to the subscriptions, written
+	// to the outbox with the change it tells about.
+	EventJob = "webhook.event"
+	// DeliverJob is the job posting a Delivery, retried with the backoff of
+	// the job queue.
+	DeliverJob = "webhook.deliver"
+)
+
+// Event is the body posted to the subscriptions.
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Owner is the user the event is about, only their subscriptions get it.
+	Owner      string    `json:"owner"`
+	OccurredOn time.Time `json:"occurred_on"`
+	Data       any       `json:"data"`
+}
+
+// EventMessage is the outbox message dispatching an event of eventType
+// about owner with data, once the transaction writing it commits.
+func EventMessage(eventType string, owner string, data any) (*outbox.Message, error) {
+	return outbox.New(EventJob, "", Event{
+		ID:         xid.New().String(),
+		Type:       eventType,
+		Owner:      owner,
+		OccurredOn: time.Now().UTC(),
+		Data:       data,
+	})
+}
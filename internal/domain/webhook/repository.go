@@ -0,0 +1,20 @@
+package webhook
+
+import "context"
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, subscription *Subscription) error
+	UpdateSubscription(ctx context.Context, subscription *Subscription) error
+	// DeleteSubscription removes the subscription and its deliveries.
+	DeleteSubscription(ctx context.Context, subscription *Subscription) error
+	GetSubscription(ctx context.Context, id string) (*Subscription, error)
+	// GetSubscriptions returns the subscriptions of createdBy, or all of them
+	// when it is empty.
+	GetSubscriptions(ctx context.Context, createdBy string) ([]Subscription, error)
+	CreateDelivery(ctx context.Context, delivery *Delivery) error
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+	GetDelivery(ctx context.Context, id string) (*Delivery, error)
+	// GetDeliveries returns the last limit deliveries of the subscription,
+	// newest first.
+	GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error)
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"emailn/internal/contract"
+	"emailn/internal/domain/job"
+	internalerrors "emailn/internal/internal-errors"
+	"emailn/internal/logging"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDeliveryQueued is returned when replaying a delivery still waiting
+	// for an attempt.
+	ErrDeliveryQueued = errors.New("the delivery is already queued")
+	// ErrUnavailable is returned when creating or changing a webhook, or
+	// replaying a delivery, without a job queue to post them.
+	ErrUnavailable = errors.New("webhooks need the job queue")
+)
+
+// deliveriesShown is how many deliveries of the log GetDeliveries returns.
+const deliveriesShown = 100
+
+type Service interface {
+	Create(ctx context.Context, newWebhook contract.NewWebhook) (*contract.WebhookResponse, error)
+	Get(ctx context.Context, createdBy string) ([]contract.WebhookResponse, error)
+	GetBy(ctx context.Context, id string, createdBy string) (*contract.WebhookResponse, error)
+	Update(ctx context.Context, id string, changed contract.NewWebhook) (*contract.WebhookResponse, error)
+	Delete(ctx context.Context, id string, createdBy string) error
+	GetDeliveries(ctx context.Context, id string, createdBy string) ([]contract.WebhookDeliveryResponse, error)
+	Replay(ctx context.Context, id string, deliveryID string, createdBy string) error
+}
+
+// JobQueue is the queue the deliveries are attempted on, see job.Queue.
+type JobQueue interface {
+	Enqueue(ctx context.Context, job *job.Job) error
+}
+
+type ServiceImp struct {
+	Repository Repository
+	// Jobs is optional, without it no webhook can be created or changed.
+	Jobs JobQueue
+	// Client posts the deliveries, defaults to NewClient giving up after 10s.
+	Client *http.Client
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+func (s *ServiceImp) Create(ctx context.Context, newWebhook contract.NewWebhook) (*contract.WebhookResponse, error) {
+	if s.Jobs == nil {
+		return nil, ErrUnavailable
+	}
+	subscription, err := NewSubscription(newWebhook.URL, newWebhook.Events, newWebhook.Secret, newWebhook.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repository.CreateSubscription(ctx, subscription); err != nil {
+		s.logger(ctx).Error("creating webhook", "error", err)
+		return nil, internalerrors.ErrInternal
+	}
+	s.logger(ctx).Info("webhook created", "webhook_id", subscription.ID, "events", subscription.Events)
+
+	response := toResponse(subscription)
+	response.Secret = subscription.Secret
+	return response, nil
+}
+
+func (s *ServiceImp) Get(ctx context.Context, createdBy string) ([]contract.WebhookResponse, error) {
+	subscriptions, err := s.Repository.GetSubscriptions(ctx, createdBy)
+	if err != nil {
+		return nil, internalerrors.ErrInternal
+	}
+	responses := make([]contract.WebhookResponse, len(subscriptions))
+	for i := range subscriptions {
+		responses[i] = *toResponse(&subscriptions[i])
+	}
+	return responses, nil
+}
+
+func (s *ServiceImp) GetBy(ctx context.Context, id string, createdBy string) (*contract.WebhookResponse, error) {
+	subscription, err := s.owned(ctx, id, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	return toResponse(subscription), nil
+}
+
+// Update replaces the URL and the events of the webhook, and its secret when
+// a new one is given.
+func (s *ServiceImp) Update(ctx context.Context, id string, changed contract.NewWebhook) (*contract.WebhookResponse, error) {
+	if s.Jobs == nil {
+		return nil, ErrUnavailable
+	}
+	subscription, err := s.owned(ctx, id, changed.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := subscription.Change(changed.URL, changed.Events, changed.Secret); err != nil {
+		return nil, err
+	}
+	if err := s.Repository.UpdateSubscription(ctx, subscription); err != nil {
+		s.logger(ctx).Error("updating webhook", "webhook_id", id, "error", err)
+		return nil, internalerrors.ErrInternal
+	}
+	s.logger(ctx).Info("webhook updated", "webhook_id", id, "events", subscription.Events)
+	return toResponse(subscription), nil
+}
+
+func (s *ServiceImp) Delete(ctx context.Context, id string, createdBy string) error {
+	subscription, err := s.owned(ctx, id, createdBy)
+	if err != nil {
+		return err
+	}
+	if err := s.Repository.DeleteSubscription(ctx, subscription); err != nil {
+		s.logger(ctx).Error("deleting webhook", "webhook_id", id, "error", err)
+		return internalerrors.ErrInternal
+	}
+	s.logger(ctx).Info("webhook deleted", "webhook_id", id)
+	return nil
+}
+
+// GetDeliveries returns the last deliveries of the webhook, newest first.
+func (s *ServiceImp) GetDeliveries(ctx context.Context, id string, createdBy string) ([]contract.WebhookDeliveryResponse, error) {
+	if _, err := s.owned(ctx, id, createdBy); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.Repository.GetDeliveries(ctx, id, deliveriesShown)
+	if err != nil {
+		return nil, internalerrors.ErrInternal
+	}
+	responses := make([]contract.WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = contract.WebhookDeliveryResponse{
+			ID:             delivery.ID,
+			EventID:        delivery.EventID,
+			EventType:      delivery.EventType,
+			Payload:        json.RawMessage(delivery.Payload),
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			ResponseStatus: delivery.ResponseStatus,
+			LastError:      delivery.LastError,
+			CreatedOn:      delivery.CreatedOn,
+			LastAttemptOn:  delivery.LastAttemptOn,
+			DeliveredOn:    delivery.DeliveredOn,
+		}
+	}
+	return responses, nil
+}
+
+// Replay posts a delivery of the webhook again, with a new round of
+// attempts, whatever its outcome was.
+func (s *ServiceImp) Replay(ctx context.Context, id string, deliveryID string, createdBy string) error {
+	if s.Jobs == nil {
+		return ErrUnavailable
+	}
+	if _, err := s.owned(ctx, id, createdBy); err != nil {
+		return err
+	}
+	delivery, err := s.Repository.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return internalerrors.ProcessErrorToReturn(err)
+	}
+	if delivery.SubscriptionID != id {
+		return gorm.ErrRecordNotFound
+	}
+
+	delivery.Replay()
+	if err := s.Repository.UpdateDelivery(ctx, delivery); err != nil {
+		s.logger(ctx).Error("replaying webhook delivery", "delivery_id", deliveryID, "error", err)
+		return internalerrors.ErrInternal
+	}
+	err = s.enqueue(ctx, delivery)
+	if errors.Is(err, job.ErrAlreadyQueued) {
+		return ErrDeliveryQueued
+	}
+	if err != nil {
+		s.logger(ctx).Error("replaying webhook delivery", "delivery_id", deliveryID, "error", err)
+		return internalerrors.ErrInternal
+	}
+	s.logger(ctx).Info("webhook delivery replayed", "webhook_id", id, "delivery_id", deliveryID)
+	return nil
+}
+
+// owned returns the subscription when createdBy made it, the others are not
+// found.
+func (s *ServiceImp) owned(ctx context.Context, id string, createdBy string) (*Subscription, error) {
+	subscription, err := s.Repository.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, internalerrors.ProcessErrorToReturn(err)
+	}
+	if subscription.CreatedBy != createdBy {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return subscription, nil
+}
+
+func (s *ServiceImp) client() *http.Client {
+	if s.Client == nil {
+		return NewClient(10 * time.Second)
+	}
+	return s.Client
+}
+
+// logger returns the logger of the request in ctx, carrying its request id,
+// or the Logger of the service.
+func (s *ServiceImp) logger(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.Logger)
+}
+
+func toResponse(subscription *Subscription) *contract.WebhookResponse {
+	return &contract.WebhookResponse{
+		ID:        subscription.ID,
+		URL:       subscription.URL,
+		Events:    subscription.Events,
+		CreatedOn: subscription.CreatedOn,
+	}
+}
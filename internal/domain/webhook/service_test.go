@@ -0,0 +1,287 @@
+package webhook_test
+
+import (
+	"context"
+	"emailn/internal/contract"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/webhook"
+	"emailn/internal/infrastructure/memory"
+	internalmock "emailn/internal/test/internal-mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+const owner = "owner@test.com"
+
+func newService() (*webhook.ServiceImp, *memory.WebhookRepository, *internalmock.JobQueueMock) {
+	repository := memory.NewWebhookRepository()
+	queue := new(internalmock.JobQueueMock)
+	return &webhook.ServiceImp{Repository: repository, Jobs: queue}, repository, queue
+}
+
+func subscribe(t *testing.T, service *webhook.ServiceImp, url string, events ...string) *contract.WebhookResponse {
+	created, err := service.Create(context.Background(), contract.NewWebhook{
+		URL: url, Events: events, Secret: "a-secret-of-the-crm", CreatedBy: owner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func eventJob(eventType string) *job.Job {
+	message, _ := webhook.EventMessage(eventType, owner, map[string]string{"campaign_id": "c1"})
+	return &job.Job{Kind: webhook.EventJob, Payload: message.Payload}
+}
+
+func deliverJob(deliveryID string, attempt int) *job.Job {
+	deliver, _ := job.New(webhook.DeliverJob, "", map[string]string{"delivery_id": deliveryID}, time.Now())
+	deliver.Attempts, deliver.MaxAttempts = attempt, 3
+	return deliver
+}
+
+// deliveryOf runs the event job and returns the only delivery it made for
+// the subscription.
+func deliveryOf(t *testing.T, service *webhook.ServiceImp, repository *memory.WebhookRepository, subscriptionID string) *webhook.Delivery {
+	if err := service.HandleEvent(context.Background(), eventJob(webhook.CampaignStarted)); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ := repository.GetDeliveries(context.Background(), subscriptionID, 10)
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries, want 1", len(deliveries))
+	}
+	return &deliveries[0]
+}
+
+func Test_Create_should_return_the_secret_only_once(t *testing.T) {
+	assert := assert.New(t)
+	service, _, _ := newService()
+
+	created, err := service.Create(context.Background(), contract.NewWebhook{
+		URL: "https://crm.example.com/hooks", Events: []string{webhook.CampaignStarted}, CreatedBy: owner,
+	})
+
+	assert.Nil(err)
+	assert.Len(created.Secret, 64)
+	got, _ := service.GetBy(context.Background(), created.ID, owner)
+	assert.Empty(got.Secret)
+}
+
+func Test_Create_should_refuse_unknown_events(t *testing.T) {
+	service, _, _ := newService()
+
+	_, err := service.Create(context.Background(), contract.NewWebhook{
+		URL: "https://crm.example.com/hooks", Events: []string{"campaign.exploded"}, CreatedBy: owner,
+	})
+
+	assert.NotNil(t, err)
+}
+
+func Test_Create_should_refuse_the_unsubscribed_event_not_sent_yet(t *testing.T) {
+	service, _, _ := newService()
+
+	_, err := service.Create(context.Background(), contract.NewWebhook{
+		URL: "https://crm.example.com/hooks", Events: []string{"contact.unsubscribed"}, CreatedBy: owner,
+	})
+
+	assert.NotNil(t, err)
+}
+
+func Test_Create_should_refuse_a_plain_http_url(t *testing.T) {
+	service, _, _ := newService()
+
+	_, err := service.Create(context.Background(), contract.NewWebhook{
+		URL: "http://crm.example.com/hooks", Events: []string{webhook.CampaignStarted}, CreatedBy: owner,
+	})
+
+	assert.NotNil(t, err)
+}
+
+func Test_Create_returnErrUnavailable_without_a_job_queue(t *testing.T) {
+	service := &webhook.ServiceImp{Repository: memory.NewWebhookRepository()}
+
+	_, err := service.Create(context.Background(), contract.NewWebhook{
+		URL: "https://crm.example.com/hooks", Events: []string{webhook.CampaignStarted}, CreatedBy: owner,
+	})
+
+	assert.Equal(t, webhook.ErrUnavailable, err)
+}
+
+func Test_GetBy_returnRecordNotFound_for_the_webhook_of_another_user(t *testing.T) {
+	service, _, _ := newService()
+	created := subscribe(t, service, "https://crm.example.com/hooks", webhook.CampaignStarted)
+
+	_, err := service.GetBy(context.Background(), created.ID, "other@test.com")
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func Test_HandleEvent_should_deliver_only_to_the_subscriptions_asking_for_it(t *testing.T) {
+	assert := assert.New(t)
+	service, repository, queue := newService()
+	started := subscribe(t, service, "https://crm.example.com/started", webhook.CampaignStarted)
+	finished := subscribe(t, service, "https://crm.example.com/finished", webhook.CampaignFinished)
+	queue.On("Enqueue", mock.MatchedBy(func(deliver *job.Job) bool {
+		return deliver.Kind == webhook.DeliverJob
+	})).Return(nil).Once()
+
+	err := service.HandleEvent(context.Background(), eventJob(webhook.CampaignStarted))
+
+	assert.Nil(err)
+	queue.AssertExpectations(t)
+	deliveries, _ := repository.GetDeliveries(context.Background(), started.ID, 10)
+	assert.Len(deliveries, 1)
+	assert.Equal(webhook.DeliveryPending, deliveries[0].Status)
+	deliveries, _ = repository.GetDeliveries(context.Background(), finished.ID, 10)
+	assert.Empty(deliveries)
+}
+
+func Test_HandleEvent_should_not_deliver_the_events_of_another_owner(t *testing.T) {
+	assert := assert.New(t)
+	service, repository, queue := newService()
+	theirs, _ := service.Create(context.Background(), contract.NewWebhook{
+		URL: "https://other.example.com/hooks", Events: []string{webhook.CampaignStarted}, CreatedBy: "other@test.com",
+	})
+
+	err := service.HandleEvent(context.Background(), eventJob(webhook.CampaignStarted))
+
+	assert.Nil(err)
+	queue.AssertNotCalled(t, "Enqueue", mock.Anything)
+	deliveries, _ := repository.GetDeliveries(context.Background(), theirs.ID, 10)
+	assert.Empty(deliveries)
+}
+
+func Test_HandleEvent_should_drop_an_event_without_owner(t *testing.T) {
+	assert := assert.New(t)
+	service, _, queue := newService()
+	subscribe(t, service, "https://crm.example.com/hooks", webhook.CampaignStarted)
+	message, _ := webhook.EventMessage(webhook.CampaignStarted, "", nil)
+
+	err := service.HandleEvent(context.Background(), &job.Job{Kind: webhook.EventJob, Payload: message.Payload})
+
+	assert.Nil(err)
+	queue.AssertNotCalled(t, "Enqueue", mock.Anything)
+}
+
+func Test_HandleDeliver_should_post_the_signed_event(t *testing.T) {
+	assert := assert.New(t)
+	var received *http.Request
+	var body []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	service, repository, queue := newService()
+	service.Client = server.Client()
+	queue.On("Enqueue", mock.Anything).Return(nil)
+	created := subscribe(t, service, server.URL, webhook.CampaignStarted)
+	delivery := deliveryOf(t, service, repository, created.ID)
+
+	err := service.HandleDeliver(context.Background(), deliverJob(delivery.ID, 1))
+
+	assert.Nil(err)
+	assert.Equal(webhook.CampaignStarted, received.Header.Get(webhook.HeaderEvent))
+	assert.Equal(delivery.ID, received.Header.Get(webhook.HeaderDelivery))
+	timestamp := received.Header.Get(webhook.HeaderTimestamp)
+	assert.Equal(webhook.Sign("a-secret-of-the-crm", timestamp, body), received.Header.Get(webhook.HeaderSignature))
+	assert.JSONEq(delivery.Payload, string(body))
+	saved, _ := repository.GetDelivery(context.Background(), delivery.ID)
+	assert.Equal(webhook.DeliverySucceeded, saved.Status)
+	assert.Equal(http.StatusOK, saved.ResponseStatus)
+}
+
+func Test_HandleDeliver_should_keep_the_delivery_pending_until_the_last_attempt(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	service, repository, queue := newService()
+	service.Client = server.Client()
+	queue.On("Enqueue", mock.Anything).Return(nil)
+	created := subscribe(t, service, server.URL, webhook.CampaignStarted)
+	delivery := deliveryOf(t, service, repository, created.ID)
+
+	err := service.HandleDeliver(context.Background(), deliverJob(delivery.ID, 1))
+
+	assert.NotNil(err)
+	saved, _ := repository.GetDelivery(context.Background(), delivery.ID)
+	assert.Equal(webhook.DeliveryPending, saved.Status)
+	assert.Equal(http.StatusServiceUnavailable, saved.ResponseStatus)
+
+	err = service.HandleDeliver(context.Background(), deliverJob(delivery.ID, 3))
+
+	assert.NotNil(err)
+	saved, _ = repository.GetDelivery(context.Background(), delivery.ID)
+	assert.Equal(webhook.DeliveryFailed, saved.Status)
+	assert.Equal(2, saved.Attempts)
+	assert.Contains(saved.LastError, "503")
+}
+
+func Test_HandleDeliver_should_not_post_to_an_internal_address(t *testing.T) {
+	assert := assert.New(t)
+	posted := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+	}))
+	defer server.Close()
+	service, repository, queue := newService()
+	queue.On("Enqueue", mock.Anything).Return(nil)
+	created := subscribe(t, service, server.URL, webhook.CampaignStarted)
+	delivery := deliveryOf(t, service, repository, created.ID)
+
+	err := service.HandleDeliver(context.Background(), deliverJob(delivery.ID, 1))
+
+	assert.NotNil(err)
+	assert.False(posted)
+	saved, _ := repository.GetDelivery(context.Background(), delivery.ID)
+	assert.Contains(saved.LastError, webhook.ErrAddressNotAllowed.Error())
+}
+
+func Test_Replay_should_queue_a_failed_delivery_again(t *testing.T) {
+	assert := assert.New(t)
+	service, repository, queue := newService()
+	queue.On("Enqueue", mock.Anything).Return(nil)
+	created := subscribe(t, service, "https://crm.example.com/hooks", webhook.CampaignStarted)
+	delivery := deliveryOf(t, service, repository, created.ID)
+	delivery.Attempted(http.StatusInternalServerError, io.ErrUnexpectedEOF, true)
+	repository.UpdateDelivery(context.Background(), delivery)
+
+	err := service.Replay(context.Background(), created.ID, delivery.ID, owner)
+
+	assert.Nil(err)
+	saved, _ := repository.GetDelivery(context.Background(), delivery.ID)
+	assert.Equal(webhook.DeliveryPending, saved.Status)
+	queue.AssertNumberOfCalls(t, "Enqueue", 2)
+}
+
+func Test_Replay_returnErrDeliveryQueued_when_the_delivery_is_queued(t *testing.T) {
+	service, repository, queue := newService()
+	queue.On("Enqueue", mock.Anything).Return(nil).Once()
+	queue.On("Enqueue", mock.Anything).Return(job.ErrAlreadyQueued)
+	created := subscribe(t, service, "https://crm.example.com/hooks", webhook.CampaignStarted)
+	delivery := deliveryOf(t, service, repository, created.ID)
+
+	err := service.Replay(context.Background(), created.ID, delivery.ID, owner)
+
+	assert.Equal(t, webhook.ErrDeliveryQueued, err)
+}
+
+func Test_Replay_returnRecordNotFound_for_the_webhook_of_another_user(t *testing.T) {
+	service, repository, queue := newService()
+	queue.On("Enqueue", mock.Anything).Return(nil)
+	created := subscribe(t, service, "https://crm.example.com/hooks", webhook.CampaignStarted)
+	delivery := deliveryOf(t, service, repository, created.ID)
+
+	err := service.Replay(context.Background(), created.ID, delivery.ID, "other@test.com")
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"crypto/rand"
+	internalerrors "emailn/internal/internal-errors"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// The event types a subscription can ask for.
+const (
+	CampaignStarted  = "campaign.started"
+	CampaignFinished = "campaign.finished"
+	// CampaignFailed is sent when a send gave up and the campaign went back
+	// to Pending.
+	CampaignFailed = "campaign.failed"
+	ContactBounced = "contact.bounced"
+	// ContactSuppressed is sent when the address of a contact goes on the
+	// suppression list, like an unsubscribe it is not mailed again.
+	ContactSuppressed = "contact.suppressed"
+)
+
+var EventTypes = []string{CampaignStarted, CampaignFinished, CampaignFailed, ContactBounced, ContactSuppressed}
+
+// Subscription asks for the events of the given types of the campaigns of
+// its creator to be posted to URL, signed with Secret. URL must be https.
+// Only its creator manages it.
+type Subscription struct {
+	ID        string   `validate:"required" gorm:"size:50"`
+	URL       string   `validate:"required,http_url,startswith=https://" gorm:"size:2048"`
+	Events    []string `validate:"min=1,dive,oneof=campaign.started campaign.finished campaign.failed contact.bounced contact.suppressed" gorm:"serializer:json;type:text"`
+	Secret    string   `validate:"min=16,max=255" gorm:"size:255"`
+	CreatedBy string   `validate:"email" gorm:"size:50;index"`
+	CreatedOn time.Time
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// NewSubscription creates the subscription, with a random secret when none
+// is given.
+func NewSubscription(url string, events []string, secret string, createdBy string) (*Subscription, error) {
+	subscription := &Subscription{
+		ID:        xid.New().String(),
+		CreatedBy: createdBy,
+		CreatedOn: time.Now(),
+	}
+	if err := subscription.Change(url, events, secret); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Change replaces the URL and the events, and the secret when one is given.
+func (s *Subscription) Change(url string, events []string, secret string) error {
+	changed := *s
+	changed.URL = url
+	changed.Events = slices.Clone(events)
+	slices.Sort(changed.Events)
+	changed.Events = slices.Compact(changed.Events)
+	if secret != "" {
+		changed.Secret = secret
+	}
+	if changed.Secret == "" {
+		changed.Secret = newSecret()
+	}
+	if err := internalerrors.ValidateStruct(&changed); err != nil {
+		return err
+	}
+	*s = changed
+	return nil
+}
+
+func (s *Subscription) Wants(eventType string) bool {
+	return slices.Contains(s.Events, eventType)
+}
+
+func newSecret() string {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return hex.EncodeToString(secret)
+}
@@ -2,50 +2,89 @@ package endpoints
 
 import (
 	"context"
+	"emailn/internal/logging"
+	"fmt"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 
 	oidc "github.com/coreos/go-oidc/v3/oidc"
 	jwtgo "github.com/dgrijalva/jwt-go"
-	"github.com/go-chi/render"
 )
 
-func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token_string := r.Header.Get("Authorization")
-		if token_string == "" {
-			render.Status(r, 401)
-			render.JSON(w, r, map[string]string{"error": "request does not contain an authorization header"})
-			return
+// Auth validates the bearer token against the OIDC provider. The provider
+// discovery runs on the first request and is kept; while the provider is
+// unreachable it is retried on every request.
+func Auth(providerURL string, clientID string) func(http.Handler) http.Handler {
+	var mutex sync.Mutex
+	var verifier *oidc.IDTokenVerifier
+
+	getVerifier := func(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if verifier != nil {
+			return verifier, nil
+		}
+		provider, err := oidc.NewProvider(ctx, providerURL)
+		if err != nil {
+			return nil, err
 		}
+		verifier = provider.Verifier(&oidc.Config{ClientID: clientID})
+		// verifier := provider.Verifier(&oidc.Config{SkipClientIDCheck: true})
+		return verifier, nil
+	}
 
-		token_string = strings.Replace(token_string, "Bearer ", "", 1)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token_string := r.Header.Get("Authorization")
+			if token_string == "" {
+				writeProblem(w, newProblem(r, http.StatusUnauthorized, "unauthorized", "request does not contain an authorization header"))
+				return
+			}
 
-		provider, err := oidc.NewProvider(r.Context(), os.Getenv("KEYCLOAK"))
+			token_string = strings.Replace(token_string, "Bearer ", "", 1)
 
-		if err != nil {
-			render.Status(r, 500)
-			render.JSON(w, r, map[string]string{"error": "error to connect to the provider"})
-			return
-		}
+			verifier, err := getVerifier(r.Context())
+			if err != nil {
+				writeProblem(w, newProblem(r, http.StatusInternalServerError, "auth_provider_unavailable", "error to connect to the provider"))
+				return
+			}
 
-		verifier := provider.Verifier(&oidc.Config{ClientID: "emailn"})
-		// verifier := provider.Verifier(&oidc.Config{SkipClientIDCheck: true})
-		_, err = verifier.Verify(r.Context(), token_string)
-		if err != nil {
-			render.Status(r, 401)
-			render.JSON(w, r, map[string]string{"error": "invalid token"})
-			return
-		}
+			_, err = verifier.Verify(r.Context(), token_string)
+			if err != nil {
+				writeProblem(w, newProblem(r, http.StatusUnauthorized, "unauthorized", "invalid token"))
+				return
+			}
+
+			token, _ := jwtgo.Parse(token_string, nil)
+			claims := token.Claims.(jwtgo.MapClaims)
+			email := claims["email"]
 
-		token, _ := jwtgo.Parse(token_string, nil)
-		claims := token.Claims.(jwtgo.MapClaims)
-		email := claims["email"]
+			ctx := context.WithValue(r.Context(), "email", email)
+			logging.With(ctx, "email", email)
 
-		ctx := context.WithValue(r.Context(), "email", email)
+			next.ServeHTTP(w, r.WithContext(ctx))
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-	})
+// OIDCHealthCheck checks the provider answers its discovery document.
+func OIDCHealthCheck(providerURL string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		url := strings.TrimSuffix(providerURL, "/") + "/.well-known/openid-configuration"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("provider answered %d", res.StatusCode)
+		}
+		return nil
+	}
 }
@@ -0,0 +1,29 @@
+package endpoints
+
+import (
+	"emailn/internal/infrastructure/bounce"
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+func (h *Handler) BouncePost(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	bounces, err := bounce.Parse(r.Body, h.BounceAddress)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	processed := 0
+	for _, b := range bounces {
+		err = h.CampaignService.ProcessBounce(r.Context(), b)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		processed++
+	}
+	return map[string]int{"processed": processed}, 202, nil
+}
@@ -0,0 +1,71 @@
+package endpoints
+
+import (
+	"emailn/internal/domain/campaign"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+const hardBounce = "To: bounces+cp1bounce000000000000@emailn.com\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=B\r\n" +
+	"\r\n" +
+	"--B\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx.emailn.com\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; nobody@test.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"\r\n" +
+	"--B--\r\n"
+
+func Test_BouncePost_should_process_bounces(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("ProcessBounce", mock.MatchedBy(func(bounce campaign.Bounce) bool {
+		return bounce.ContactID == "cp1bounce000000000000" && bounce.Status == "5.1.1"
+	})).Return(nil)
+	handler := Handler{CampaignService: service, BounceAddress: "bounces@emailn.com"}
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(hardBounce))
+	res := httptest.NewRecorder()
+
+	response, status, err := handler.BouncePost(res, req)
+
+	assert.Nil(err)
+	assert.Equal(202, status)
+	assert.Equal(map[string]int{"processed": 1}, response)
+}
+
+func Test_BouncePost_should_ignore_unknown_contacts(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("ProcessBounce", mock.Anything).Return(gorm.ErrRecordNotFound)
+	handler := Handler{CampaignService: service, BounceAddress: "bounces@emailn.com"}
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(hardBounce))
+	res := httptest.NewRecorder()
+
+	response, _, err := handler.BouncePost(res, req)
+
+	assert.Nil(err)
+	assert.Equal(map[string]int{"processed": 0}, response)
+}
+
+func Test_BouncePost_should_inform_error_when_body_is_not_a_dsn(t *testing.T) {
+	assert := assert.New(t)
+	handler := Handler{CampaignService: new(internalmock.CampaignServiceMock)}
+	req, _ := http.NewRequest("POST", "/", strings.NewReader("Subject: hi\r\n\r\nhello"))
+	res := httptest.NewRecorder()
+
+	_, _, err := handler.BouncePost(res, req)
+
+	assert.NotNil(err)
+}
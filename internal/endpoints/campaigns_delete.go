@@ -1,6 +1,7 @@
 package endpoints
 
 import (
+	"emailn/internal/logging"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -8,6 +9,7 @@ import (
 
 func (h *Handler) CampaignDelete(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
 	id := chi.URLParam(r, "id")
-	err := h.CampaignService.Delete(id)
+	logging.With(r.Context(), "campaign_id", id)
+	err := h.CampaignService.Delete(r.Context(), id)
 	return nil, 200, err
 }
@@ -0,0 +1,74 @@
+package endpoints
+
+import (
+	"emailn/internal/contract"
+	"emailn/internal/domain/campaign"
+	internalerrors "emailn/internal/internal-errors"
+	"emailn/internal/logging"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// eventsHeartbeat is how often an idle stream gets a comment, so proxies do
+// not close it.
+const eventsHeartbeat = 15 * time.Second
+
+// CampaignEvents streams the status changes and the progress of the send of
+// the campaign as Server-Sent Events until the client leaves. A client
+// reconnecting with Last-Event-ID gets the events it missed, or the current
+// state when they are gone.
+func (h *Handler) CampaignEvents(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "campaign_id", id)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, 0, internalerrors.ErrInternal
+	}
+	stream, err := h.CampaignService.Follow(r.Context(), id, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range stream.Missed {
+		writeEvent(w, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-stream.Events:
+			if !ok {
+				// fell behind or shutting down, the client resumes on reconnect
+				return nil, http.StatusOK, nil
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return nil, http.StatusOK, nil
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event campaign.ProgressEvent) {
+	data, _ := json.Marshal(contract.CampaignProgressResponse{
+		ID:     event.CampaignID,
+		Status: event.Status,
+		Total:  event.Total,
+		Sent:   event.Sent,
+		Failed: event.Failed,
+	})
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
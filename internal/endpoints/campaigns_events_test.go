@@ -0,0 +1,73 @@
+package endpoints
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func eventsRequest(id string, lastEventID string) *http.Request {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Last-Event-ID", lastEventID)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+}
+
+func Test_CampaignEvents_should_stream_the_state_and_the_events(t *testing.T) {
+	assert := assert.New(t)
+	progress := campaign.NewProgress()
+	stream, _ := progress.Follow("c1", "")
+	stream.Missed = []campaign.ProgressEvent{{ID: "r.1", Type: campaign.ProgressStatus, CampaignID: "c1", Status: campaign.Started, Total: 3}}
+	progress.Publish(campaign.ProgressEvent{Type: campaign.ProgressBatch, CampaignID: "c1", Status: campaign.Started, Total: 3, Sent: 2})
+	progress.Close()
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Follow", "c1", "r.0").Return(stream, nil)
+	handler := Handler{CampaignService: service}
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.CampaignEvents)(res, eventsRequest("c1", "r.0"))
+
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("text/event-stream", res.Header().Get("Content-Type"))
+	assert.Contains(res.Body.String(),
+		"id: r.1\nevent: status\ndata: {\"ID\":\"c1\",\"Status\":\"Started\",\"Total\":3,\"Sent\":0,\"Failed\":0}\n\n")
+	assert.Contains(res.Body.String(), "event: progress\ndata: {\"ID\":\"c1\",\"Status\":\"Started\",\"Total\":3,\"Sent\":2,\"Failed\":0}\n\n")
+}
+
+func Test_CampaignEvents_should_stop_when_the_client_leaves(t *testing.T) {
+	progress := campaign.NewProgress()
+	stream, _ := progress.Follow("c1", "")
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Follow", "c1", "").Return(stream, nil)
+	handler := Handler{CampaignService: service}
+	req := eventsRequest("c1", "")
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.CampaignEvents)(res, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func Test_CampaignEvents_should_return_not_found_when_the_campaign_does_not_exist(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Follow", "c1", "").Return(nil, gorm.ErrRecordNotFound)
+	handler := Handler{CampaignService: service}
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.CampaignEvents)(res, eventsRequest("c1", ""))
+
+	assert.Equal(http.StatusNotFound, res.Code)
+	assert.Contains(res.Body.String(), `"code":"not_found"`)
+}
@@ -1,7 +1,7 @@
 package endpoints
 
 import (
-	"fmt"
+	"emailn/internal/logging"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,10 +9,13 @@ import (
 
 func (h *Handler) CampaignGetById(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
 	id := chi.URLParam(r, "id")
-	campaign, err := h.CampaignService.GetBy(id)
+	logging.With(r.Context(), "campaign_id", id)
+	campaign, err := h.CampaignService.GetBy(r.Context(), id)
 	if err == nil && campaign == nil {
 		return nil, http.StatusNotFound, err
 	}
-	fmt.Println("hendeler " + id)
+	if err == nil {
+		w.Header().Set("ETag", etag(campaign.Version))
+	}
 	return campaign, 200, err
 }
@@ -48,3 +48,16 @@ func Test_CampaignGetById_should_return_error_when_something_wrong(t *testing.T)
 	assert.Equal(errExpected.Error(), errReturned.Error())
 
 }
+
+func Test_CampaignGetById_should_return_the_version_as_ETag(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("GetBy", mock.Anything).Return(&contract.CampaignResponse{ID: "343", Version: 3}, nil)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+
+	handler.CampaignGetById(res, req)
+
+	assert.Equal(`"3"`, res.Header().Get("ETag"))
+}
@@ -0,0 +1,15 @@
+package endpoints
+
+import (
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) CampaignGetStats(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "campaign_id", id)
+	stats, err := h.CampaignService.GetStats(r.Context(), id)
+	return stats, 200, err
+}
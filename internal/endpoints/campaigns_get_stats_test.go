@@ -0,0 +1,50 @@
+package endpoints
+
+import (
+	"emailn/internal/contract"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_CampaignGetStats_should_return_stats(t *testing.T) {
+	assert := assert.New(t)
+	stats := contract.CampaignStatsResponse{
+		ID:     "343",
+		Status: "Done",
+		Total:  3,
+		Sent:   2,
+		Failed: 1,
+	}
+	service := new(internalmock.CampaignServiceMock)
+	service.On("GetStats", mock.Anything).Return(&stats, nil)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+
+	response, status, _ := handler.CampaignGetStats(res, req)
+
+	assert.Equal(200, status)
+	assert.Equal(stats.ID, response.(*contract.CampaignStatsResponse).ID)
+	assert.Equal(stats.Sent, response.(*contract.CampaignStatsResponse).Sent)
+}
+
+func Test_CampaignGetStats_should_return_error_when_something_wrong(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	errExpected := errors.New("something whong")
+	service.On("GetStats", mock.Anything).Return(nil, errExpected)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+
+	_, _, errReturned := handler.CampaignGetStats(res, req)
+
+	assert.Equal(errExpected.Error(), errReturned.Error())
+}
@@ -1,17 +1,63 @@
 package endpoints
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"emailn/internal/contract"
+	"emailn/internal/logging"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/go-chi/render"
 )
 
 func (h *Handler) CampaignPost(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
-	var request contract.NewCampaign
 	email := r.Context().Value("email").(string)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || h.IdempotencyService == nil {
+		return h.createCampaign(r, body, email)
+	}
+
+	hash := sha256.Sum256(body)
+	record, err := h.IdempotencyService.Begin(r.Context(), key, email, hex.EncodeToString(hash[:]))
+	if err != nil {
+		return nil, 0, err
+	}
+	if record.Completed() {
+		var response map[string]string
+		json.Unmarshal([]byte(record.Response), &response)
+		w.Header().Set("Idempotent-Replayed", "true")
+		return response, record.StatusCode, nil
+	}
+
+	response, status, err := h.createCampaign(r, body, email)
+	if err != nil {
+		h.IdempotencyService.Abort(r.Context(), record)
+		return response, status, err
+	}
+	saved, _ := json.Marshal(response)
+	// the campaign exists already, failing to remember the key only means a
+	// retry gets a conflict instead of the original response
+	if err := h.IdempotencyService.Complete(r.Context(), record, status, string(saved)); err != nil {
+		logging.FromContext(r.Context(), nil).Warn("completing idempotency key", "error", err)
+	}
+	return response, status, nil
+}
+
+func (h *Handler) createCampaign(r *http.Request, body []byte, email string) (map[string]string, int, error) {
+	var request contract.NewCampaign
+	render.DecodeJSON(bytes.NewReader(body), &request)
 	request.CreatedBy = email
-	render.DecodeJSON(r.Body, &request)
-	id, err := h.CampaignService.Create(request)
+	id, err := h.CampaignService.Create(r.Context(), request)
+	if err == nil {
+		logging.With(r.Context(), "campaign_id", id)
+	}
 	return map[string]string{"id": id}, 201, err
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"emailn/internal/contract"
+	"emailn/internal/domain/idempotency"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -71,3 +72,77 @@ func Test_CampaignPost_should_inform_error_when_exist(t *testing.T) {
 	assert.NotNil(err)
 
 }
+
+func Test_CampaignPost_should_return_original_response_when_idempotency_key_is_repeated(t *testing.T) {
+	assert := assert.New(t)
+	body := contract.NewCampaign{Name: "teste", Content: "Hi everyone", Emails: []string{"teste@test.com"}}
+	service := new(internalmock.CampaignServiceMock)
+	idempotencyService := new(internalmock.IdempotencyServiceMock)
+	idempotencyService.On("Begin", "key1", "teste@teste.com.br", mock.Anything).
+		Return(&idempotency.Record{StatusCode: 201, Response: `{"id":"34x"}`}, nil)
+	handler := Handler{CampaignService: service, IdempotencyService: idempotencyService}
+	req, res := setup(body, "teste@teste.com.br")
+	req.Header.Set("Idempotency-Key", "key1")
+
+	response, status, err := handler.CampaignPost(res, req)
+
+	assert.Nil(err)
+	assert.Equal(201, status)
+	assert.Equal(map[string]string{"id": "34x"}, response)
+	assert.Equal("true", res.Header().Get("Idempotent-Replayed"))
+	service.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func Test_CampaignPost_should_return_422_when_idempotency_key_is_reused_with_other_body(t *testing.T) {
+	assert := assert.New(t)
+	body := contract.NewCampaign{Name: "teste", Content: "Hi everyone", Emails: []string{"teste@test.com"}}
+	idempotencyService := new(internalmock.IdempotencyServiceMock)
+	idempotencyService.On("Begin", mock.Anything, mock.Anything, mock.Anything).Return(nil, idempotency.ErrKeyReused)
+	handler := Handler{CampaignService: new(internalmock.CampaignServiceMock), IdempotencyService: idempotencyService}
+	req, res := setup(body, "teste@teste.com.br")
+	req.Header.Set("Idempotency-Key", "key1")
+
+	HandlerError(handler.CampaignPost).ServeHTTP(res, req)
+
+	assert.Equal(422, res.Code)
+	assert.Contains(res.Body.String(), `"code":"idempotency_key_reused"`)
+}
+
+func Test_CampaignPost_should_remember_response_of_new_idempotency_key(t *testing.T) {
+	assert := assert.New(t)
+	body := contract.NewCampaign{Name: "teste", Content: "Hi everyone", Emails: []string{"teste@test.com"}}
+	record := &idempotency.Record{Key: "key1"}
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Create", mock.Anything).Return("34x", nil)
+	idempotencyService := new(internalmock.IdempotencyServiceMock)
+	idempotencyService.On("Begin", mock.Anything, mock.Anything, mock.Anything).Return(record, nil)
+	idempotencyService.On("Complete", record, 201, `{"id":"34x"}`).Return(nil)
+	handler := Handler{CampaignService: service, IdempotencyService: idempotencyService}
+	req, res := setup(body, "teste@teste.com.br")
+	req.Header.Set("Idempotency-Key", "key1")
+
+	_, status, err := handler.CampaignPost(res, req)
+
+	assert.Nil(err)
+	assert.Equal(201, status)
+	idempotencyService.AssertExpectations(t)
+}
+
+func Test_CampaignPost_should_forget_idempotency_key_when_creation_fails(t *testing.T) {
+	assert := assert.New(t)
+	body := contract.NewCampaign{Name: "teste", Content: "Hi everyone", Emails: []string{"teste@test.com"}}
+	record := &idempotency.Record{Key: "key1"}
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Create", mock.Anything).Return("", fmt.Errorf("error"))
+	idempotencyService := new(internalmock.IdempotencyServiceMock)
+	idempotencyService.On("Begin", mock.Anything, mock.Anything, mock.Anything).Return(record, nil)
+	idempotencyService.On("Abort", record).Return(nil)
+	handler := Handler{CampaignService: service, IdempotencyService: idempotencyService}
+	req, res := setup(body, "teste@teste.com.br")
+	req.Header.Set("Idempotency-Key", "key1")
+
+	_, _, err := handler.CampaignPost(res, req)
+
+	assert.NotNil(err)
+	idempotencyService.AssertExpectations(t)
+}
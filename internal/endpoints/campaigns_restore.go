@@ -0,0 +1,18 @@
+package endpoints
+
+import (
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) CampaignRestore(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "campaign_id", id)
+	err := h.CampaignService.Restore(r.Context(), id)
+	if err == nil {
+		h.setETag(w, r, id)
+	}
+	return nil, 200, err
+}
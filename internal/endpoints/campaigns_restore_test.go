@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"emailn/internal/contract"
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func Test_CampaignRestore_should_restore_campaign(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Restore", mock.Anything).Return(nil)
+	service.On("GetBy", mock.Anything).Return(&contract.CampaignResponse{Version: 5}, nil)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("POST", "/", nil)
+	res := httptest.NewRecorder()
+
+	_, status, err := handler.CampaignRestore(res, req)
+
+	assert.Equal(200, status)
+	assert.Nil(err)
+	assert.Equal(`"5"`, res.Header().Get("ETag"))
+}
+
+func Test_CampaignRestore_should_return_not_found_when_campaign_is_not_deleted(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Restore", mock.Anything).Return(gorm.ErrRecordNotFound)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("POST", "/", nil)
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.CampaignRestore)(res, req)
+
+	assert.Equal(http.StatusNotFound, res.Code)
+	assert.Contains(res.Body.String(), `"code":"not_found"`)
+}
@@ -0,0 +1,45 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"emailn/internal/contract"
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_CampaignResume_should_resume_campaign(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Resume", mock.Anything).Return(nil)
+	service.On("GetBy", mock.Anything).Return(&contract.CampaignResponse{Version: 4}, nil)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("PATCH", "/", nil)
+	res := httptest.NewRecorder()
+
+	_, status, err := handler.CampaignResume(res, req)
+
+	assert.Equal(200, status)
+	assert.Nil(err)
+	assert.Equal(`"4"`, res.Header().Get("ETag"))
+}
+
+func Test_CampaignResume_should_return_error_when_something_wrong(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	errExpected := errors.New("Campaign status invalid")
+	service.On("Resume", mock.Anything).Return(errExpected)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("PATCH", "/", nil)
+	res := httptest.NewRecorder()
+
+	_, _, err := handler.CampaignResume(res, req)
+
+	assert.Equal(errExpected.Error(), err.Error())
+	assert.Empty(res.Header().Get("ETag"))
+}
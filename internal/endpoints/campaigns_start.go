@@ -1,13 +1,35 @@
 package endpoints
 
 import (
+	"emailn/internal/logging"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// CampaignStart starts the campaign now, or at the RFC 3339 time of the at
+// query parameter.
 func (h *Handler) CampaignStart(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
 	id := chi.URLParam(r, "id")
-	err := h.CampaignService.Start(id)
-	return nil, 200, err
+	logging.With(r.Context(), "campaign_id", id)
+
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		err := h.CampaignService.Start(r.Context(), id)
+		if err == nil {
+			h.setETag(w, r, id)
+		}
+		return nil, 200, err
+	}
+	runAt, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return nil, 0, errors.New("at must be an RFC 3339 time")
+	}
+	err = h.CampaignService.Schedule(r.Context(), id, runAt)
+	if err == nil {
+		h.setETag(w, r, id)
+	}
+	return map[string]time.Time{"scheduled_at": runAt.UTC()}, 202, err
 }
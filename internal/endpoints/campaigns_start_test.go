@@ -0,0 +1,64 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"emailn/internal/contract"
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_CampaignStart_should_start_campaign(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	service.On("Start", mock.Anything).Return(nil)
+	service.On("GetBy", mock.Anything).Return(&contract.CampaignResponse{Version: 3}, nil)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("PATCH", "/", nil)
+	res := httptest.NewRecorder()
+
+	_, status, err := handler.CampaignStart(res, req)
+
+	assert.Equal(200, status)
+	assert.Nil(err)
+	assert.Equal(`"3"`, res.Header().Get("ETag"))
+}
+
+func Test_CampaignStart_should_schedule_campaign_when_at_is_given(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	at := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+	service.On("Schedule", mock.Anything, mock.MatchedBy(func(runAt time.Time) bool {
+		return runAt.Equal(at)
+	})).Return(nil)
+	service.On("GetBy", mock.Anything).Return(&contract.CampaignResponse{Version: 2}, nil)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("PATCH", "/?at=2030-01-02T12:04:05-03:00", nil)
+	res := httptest.NewRecorder()
+
+	body, status, err := handler.CampaignStart(res, req)
+
+	assert.Equal(202, status)
+	assert.Nil(err)
+	assert.Equal(map[string]time.Time{"scheduled_at": at}, body)
+	assert.Equal(`"2"`, res.Header().Get("ETag"))
+	service.AssertNotCalled(t, "Start", mock.Anything)
+}
+
+func Test_CampaignStart_should_return_error_when_at_is_invalid(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.CampaignServiceMock)
+	handler := Handler{CampaignService: service}
+	req, _ := http.NewRequest("PATCH", "/?at=tomorrow", nil)
+	res := httptest.NewRecorder()
+
+	_, _, err := handler.CampaignStart(res, req)
+
+	assert.EqualError(err, "at must be an RFC 3339 time")
+	service.AssertNotCalled(t, "Schedule", mock.Anything, mock.Anything)
+}
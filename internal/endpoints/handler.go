@@ -1,7 +1,22 @@
 package endpoints
 
-import "emailn/internal/domain/campaign"
+import (
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"emailn/internal/domain/webhook"
+	"time"
+)
 
 type Handler struct {
 	CampaignService campaign.Service
+	// IdempotencyService is optional, without it the Idempotency-Key header
+	// is ignored.
+	IdempotencyService idempotency.Service
+	WebhookService     webhook.Service
+	// BounceAddress is the envelope sender of the campaigns, the bounces
+	// posted are matched to their contacts through its VERP addresses.
+	BounceAddress string
+	HealthChecks  []HealthCheck
+	// HealthTimeout bounds how long /readyz waits for the checks.
+	HealthTimeout time.Duration
 }
@@ -1,12 +1,10 @@
 package endpoints
 
 import (
-	internalerrors "emailn/internal/internal-errors"
-	"errors"
+	"emailn/internal/logging"
 	"net/http"
 
 	"github.com/go-chi/render"
-	"gorm.io/gorm"
 )
 
 type EndpointFunc func(w http.ResponseWriter, r *http.Request) (interface{}, int, error)
@@ -15,14 +13,14 @@ func HandlerError(endpointFunc EndpointFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		obj, status, err := endpointFunc(w, r)
 		if err != nil {
-			if errors.Is(err, internalerrors.ErrInternal) {
-				render.Status(r, 500)
-			} else if errors.Is(err, gorm.ErrRecordNotFound) {
-				render.Status(r, 404)
+			problem := problemFor(r, err)
+			logger := logging.FromContext(r.Context(), nil)
+			if problem.Status >= 500 {
+				logger.Error("request failed", "code", problem.Code, "error", err)
 			} else {
-				render.Status(r, 400)
+				logger.Debug("request rejected", "code", problem.Code, "error", err)
 			}
-			render.JSON(w, r, map[string]string{"error": err.Error()})
+			writeProblem(w, problem)
 			return
 		}
 		render.Status(r, status)
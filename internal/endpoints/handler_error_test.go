@@ -1,6 +1,7 @@
 package endpoints
 
 import (
+	"emailn/internal/domain/campaign"
 	internalerrors "emailn/internal/internal-errors"
 	"encoding/json"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,3 +58,46 @@ func Test_HandlerError_when_endpoint_returns_obj_and_status(t *testing.T) {
 	assert.Equal(objExpected, objReturned)
 
 }
+
+func Test_HandlerError_should_return_problem_details(t *testing.T) {
+	assert := assert.New(t)
+	endpoint := func(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+		return nil, 0, campaign.ErrStatusInvalid
+	}
+	handlerFunc := middleware.RequestID(HandlerError(endpoint))
+	req, _ := http.NewRequest("PATCH", "/campaigns/start/1", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	res := httptest.NewRecorder()
+
+	handlerFunc.ServeHTTP(res, req)
+
+	assert.Equal(http.StatusConflict, res.Code)
+	assert.Equal("application/problem+json", res.Header().Get("Content-Type"))
+	var problem Problem
+	json.Unmarshal(res.Body.Bytes(), &problem)
+	assert.Equal("invalid_status", problem.Code)
+	assert.Equal(http.StatusConflict, problem.Status)
+	assert.Equal("req-1", problem.RequestID)
+	assert.Equal("/campaigns/start/1", problem.Instance)
+}
+
+func Test_HandlerError_should_list_every_invalid_field(t *testing.T) {
+	assert := assert.New(t)
+	endpoint := func(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+		_, err := campaign.NewCampaign("", "", []string{}, "")
+		return nil, 0, err
+	}
+	handlerFunc := HandlerError(endpoint)
+	req, _ := http.NewRequest("POST", "/campaigns", nil)
+	res := httptest.NewRecorder()
+
+	handlerFunc.ServeHTTP(res, req)
+
+	assert.Equal(http.StatusBadRequest, res.Code)
+	var problem Problem
+	json.Unmarshal(res.Body.Bytes(), &problem)
+	assert.Equal("validation_failed", problem.Code)
+	assert.Contains(problem.Errors, FieldProblem{Field: "name", Rule: "min", Param: "5", Message: "name is required with min 5"})
+	assert.Contains(problem.Errors, FieldProblem{Field: "contacts", Rule: "min", Param: "1", Message: "contacts is required with min 1"})
+	assert.Greater(len(problem.Errors), 2)
+}
@@ -0,0 +1,91 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthCheck probes one dependency of the API for /readyz.
+type HealthCheck struct {
+	Name string
+	// Critical checks make /readyz fail, the others are only reported.
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type healthResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Healthz only tells the process is alive and serving requests.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	return map[string]string{"status": "ok"}, 200, nil
+}
+
+// Readyz runs every health check in parallel and answers 503 when a critical
+// one fails.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	timeout := h.HealthTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	results := make(map[string]healthResult, len(h.HealthChecks))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.HealthChecks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(ctx)
+			result := healthResult{
+				Status:    "ok",
+				Critical:  check.Critical,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+			mutex.Lock()
+			results[check.Name] = result
+			mutex.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	status, code := "ok", http.StatusOK
+	for _, result := range results {
+		if result.Status != "ok" && result.Critical {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	return map[string]interface{}{"status": status, "checks": results}, code, nil
+}
+
+// CachedCheck keeps the result of check for ttl, for dependencies too slow or
+// too costly to probe on every call.
+func CachedCheck(check func(ctx context.Context) error, ttl time.Duration) func(ctx context.Context) error {
+	var mutex sync.Mutex
+	var checkedOn time.Time
+	var lastErr error
+
+	return func(ctx context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !checkedOn.IsZero() && time.Since(checkedOn) < ttl {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		checkedOn = time.Now()
+		return lastErr
+	}
+}
@@ -0,0 +1,94 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errors.New("connection refused") }
+
+func Test_Healthz_should_return_ok(t *testing.T) {
+	assert := assert.New(t)
+	handler := Handler{HealthChecks: []HealthCheck{{Name: "database", Critical: true, Check: failing}}}
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	res := httptest.NewRecorder()
+
+	_, status, err := handler.Healthz(res, req)
+
+	assert.Equal(200, status)
+	assert.Nil(err)
+}
+
+func Test_Readyz_should_return_ok_when_all_checks_pass(t *testing.T) {
+	assert := assert.New(t)
+	handler := Handler{HealthChecks: []HealthCheck{
+		{Name: "database", Critical: true, Check: ok},
+		{Name: "oidc", Critical: true, Check: ok},
+	}}
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	res := httptest.NewRecorder()
+
+	body, status, err := handler.Readyz(res, req)
+
+	assert.Equal(200, status)
+	assert.Nil(err)
+	response := body.(map[string]interface{})
+	assert.Equal("ok", response["status"])
+	assert.Len(response["checks"], 2)
+}
+
+func Test_Readyz_should_return_503_when_critical_check_fails(t *testing.T) {
+	assert := assert.New(t)
+	handler := Handler{HealthChecks: []HealthCheck{
+		{Name: "database", Critical: true, Check: failing},
+		{Name: "oidc", Critical: true, Check: ok},
+	}}
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	res := httptest.NewRecorder()
+
+	body, status, _ := handler.Readyz(res, req)
+
+	assert.Equal(503, status)
+	checks := body.(map[string]interface{})["checks"].(map[string]healthResult)
+	assert.Equal("fail", checks["database"].Status)
+	assert.Equal("connection refused", checks["database"].Error)
+	assert.Equal("ok", checks["oidc"].Status)
+}
+
+func Test_Readyz_should_stay_ready_when_non_critical_check_fails(t *testing.T) {
+	assert := assert.New(t)
+	handler := Handler{HealthChecks: []HealthCheck{
+		{Name: "database", Critical: true, Check: ok},
+		{Name: "smtp", Check: failing},
+	}}
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	res := httptest.NewRecorder()
+
+	body, status, _ := handler.Readyz(res, req)
+
+	assert.Equal(200, status)
+	checks := body.(map[string]interface{})["checks"].(map[string]healthResult)
+	assert.Equal("fail", checks["smtp"].Status)
+}
+
+func Test_CachedCheck_should_reuse_result_within_ttl(t *testing.T) {
+	assert := assert.New(t)
+	calls := 0
+	check := CachedCheck(func(ctx context.Context) error {
+		calls++
+		return nil
+	}, time.Minute)
+
+	check(context.Background())
+	check(context.Background())
+
+	assert.Equal(1, calls)
+}
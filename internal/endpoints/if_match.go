@@ -0,0 +1,53 @@
+package endpoints
+
+import (
+	"emailn/internal/domain/campaign"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag is the ETag of a campaign at version.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag sets the ETag of the campaign as it is after a change succeeded,
+// for the next change to send in If-Match without a GET first.
+func (h *Handler) setETag(w http.ResponseWriter, r *http.Request, id string) {
+	campaign, err := h.CampaignService.GetBy(r.Context(), id)
+	if err == nil && campaign != nil {
+		w.Header().Set("ETag", etag(campaign.Version))
+	}
+}
+
+// IfMatch hands the ETags of the If-Match header of the requests changing a
+// campaign to its operations, which fail with 412 Precondition Failed when
+// the campaign is at another version. Only strong ETags of GET
+// /campaigns/{id} can match, * matches any version.
+func IfMatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := strings.TrimSpace(r.Header.Get("If-Match"))
+		if header == "" || header == "*" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var versions []int
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+				continue
+			}
+			version, err := strconv.Atoi(tag[1 : len(tag)-1])
+			if err == nil {
+				versions = append(versions, version)
+			}
+		}
+		if len(versions) == 0 {
+			writeProblem(w, newProblem(r, http.StatusPreconditionFailed, "precondition_failed", "If-Match holds no ETag of a campaign"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(campaign.ExpectVersions(r.Context(), versions)))
+	})
+}
@@ -0,0 +1,79 @@
+package endpoints
+
+import (
+	"emailn/internal/domain/campaign"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// versionCheck deletes a Pending campaign at version, answering 200 when
+// the campaign matches the request.
+func versionCheck(version int) http.Handler {
+	repository := new(internalmock.CampaignRepositoryMock)
+	repository.On("GetBy", mock.Anything).Return(&campaign.Campaign{ID: "1", Status: campaign.Peding, Version: version}, nil)
+	repository.On("Delete", mock.Anything).Return(nil)
+	service := &campaign.ServiceImp{Repository: repository}
+	return HandlerError(func(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+		return nil, http.StatusOK, service.Delete(r.Context(), "1")
+	})
+}
+
+func Test_IfMatch_should_pass_the_matching_version(t *testing.T) {
+	req, _ := http.NewRequest("PATCH", "/", nil)
+	req.Header.Set("If-Match", `"1", "2"`)
+	res := httptest.NewRecorder()
+
+	IfMatch(versionCheck(2)).ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func Test_IfMatch_should_answer_412_when_the_version_changed(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("PATCH", "/", nil)
+	req.Header.Set("If-Match", `"1"`)
+	res := httptest.NewRecorder()
+
+	IfMatch(versionCheck(2)).ServeHTTP(res, req)
+
+	assert.Equal(http.StatusPreconditionFailed, res.Code)
+	assert.Contains(res.Body.String(), `"code":"precondition_failed"`)
+}
+
+func Test_IfMatch_should_answer_412_without_a_strong_ETag(t *testing.T) {
+	req, _ := http.NewRequest("DELETE", "/", nil)
+	req.Header.Set("If-Match", `W/"2"`)
+	res := httptest.NewRecorder()
+
+	IfMatch(versionCheck(2)).ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+}
+
+func Test_IfMatch_should_pass_any_version_without_If_Match_or_with_a_star(t *testing.T) {
+	for _, header := range []string{"", "*"} {
+		req, _ := http.NewRequest("PATCH", "/", nil)
+		req.Header.Set("If-Match", header)
+		res := httptest.NewRecorder()
+
+		IfMatch(versionCheck(7)).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code, header)
+	}
+}
+
+func Test_IfMatch_should_ignore_reads(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("If-Match", `"1"`)
+	res := httptest.NewRecorder()
+
+	IfMatch(versionCheck(2)).ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+}
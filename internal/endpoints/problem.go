@@ -0,0 +1,103 @@
+package endpoints
+
+import (
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"emailn/internal/domain/webhook"
+	internalerrors "emailn/internal/internal-errors"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gorm.io/gorm"
+)
+
+// Problem is an RFC 7807 problem details body. Code is stable and meant for
+// programs; Detail is for people and may change.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	Code      string         `json:"code"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem is one invalid field of a validation problem.
+type FieldProblem struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type problemKind struct {
+	err    error
+	status int
+	code   string
+}
+
+// problemKinds maps the errors the domain returns to their status and code,
+// the first match wins. Any other error is a bad request.
+var problemKinds = []problemKind{
+	{internalerrors.ErrInternal, http.StatusInternalServerError, "internal_error"},
+	{gorm.ErrRecordNotFound, http.StatusNotFound, "not_found"},
+	{campaign.ErrStatusInvalid, http.StatusConflict, "invalid_status"},
+	{campaign.ErrShuttingDown, http.StatusServiceUnavailable, "shutting_down"},
+	{campaign.ErrVersionConflict, http.StatusConflict, "version_conflict"},
+	{campaign.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{campaign.ErrSendInterrupted, http.StatusServiceUnavailable, "send_interrupted"},
+	{campaign.ErrSendRefused, http.StatusBadGateway, "send_refused"},
+	{campaign.ErrSendQueued, http.StatusConflict, "send_already_queued"},
+	{campaign.ErrSchedulingUnavailable, http.StatusNotImplemented, "scheduling_unavailable"},
+	{campaign.ErrFollowUnavailable, http.StatusNotImplemented, "events_unavailable"},
+	{webhook.ErrDeliveryQueued, http.StatusConflict, "delivery_already_queued"},
+	{webhook.ErrUnavailable, http.StatusNotImplemented, "webhooks_unavailable"},
+	{idempotency.ErrKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+	{idempotency.ErrInProgress, http.StatusConflict, "idempotency_key_in_progress"},
+}
+
+// problemFor builds the problem describing err for the request.
+func problemFor(r *http.Request, err error) Problem {
+	var validationError *internalerrors.ValidationError
+	if errors.As(err, &validationError) {
+		problem := newProblem(r, http.StatusBadRequest, "validation_failed", err.Error())
+		for _, field := range validationError.Fields {
+			problem.Errors = append(problem.Errors, FieldProblem{
+				Field:   field.Field,
+				Rule:    field.Rule,
+				Param:   field.Param,
+				Message: field.Message,
+			})
+		}
+		return problem
+	}
+
+	for _, kind := range problemKinds {
+		if errors.Is(err, kind.err) {
+			return newProblem(r, kind.status, kind.code, err.Error())
+		}
+	}
+	return newProblem(r, http.StatusBadRequest, "bad_request", err.Error())
+}
+
+func newProblem(r *http.Request, status int, code string, detail string) Problem {
+	return Problem{
+		Type:      "urn:emailn:problem:" + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,21 @@
+package endpoints
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// WebhookAuth protects the endpoints called by the mail server, which cannot
+// get an OIDC token, with a shared secret. An empty secret rejects every call.
+func WebhookAuth(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Webhook-Token")
+			if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+				writeProblem(w, newProblem(r, http.StatusUnauthorized, "unauthorized", "invalid webhook token"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
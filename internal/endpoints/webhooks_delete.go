@@ -0,0 +1,16 @@
+package endpoints
+
+import (
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) WebhookDelete(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "webhook_id", id)
+	email := r.Context().Value("email").(string)
+	err := h.WebhookService.Delete(r.Context(), id, email)
+	return nil, 200, err
+}
@@ -0,0 +1,27 @@
+package endpoints
+
+import (
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookDeliveries returns the last deliveries of the webhook, newest first.
+func (h *Handler) WebhookDeliveries(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "webhook_id", id)
+	email := r.Context().Value("email").(string)
+	deliveries, err := h.WebhookService.GetDeliveries(r.Context(), id, email)
+	return deliveries, 200, err
+}
+
+// WebhookReplay queues a delivery to be posted again.
+func (h *Handler) WebhookReplay(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	deliveryID := chi.URLParam(r, "deliveryId")
+	logging.With(r.Context(), "webhook_id", id, "delivery_id", deliveryID)
+	email := r.Context().Value("email").(string)
+	err := h.WebhookService.Replay(r.Context(), id, deliveryID, email)
+	return map[string]string{"id": deliveryID}, 202, err
+}
@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"context"
+	"emailn/internal/domain/webhook"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func replayRequest(id string, deliveryID string) *http.Request {
+	req, _ := http.NewRequest("POST", "/", nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", id)
+	routeContext.URLParams.Add("deliveryId", deliveryID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+	return req.WithContext(context.WithValue(ctx, "email", "teste1@teste.com.br"))
+}
+
+func Test_WebhookReplay_should_queue_the_delivery(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.WebhookServiceMock)
+	service.On("Replay", "w1", "d1", "teste1@teste.com.br").Return(nil)
+	handler := Handler{WebhookService: service}
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.WebhookReplay)(res, replayRequest("w1", "d1"))
+
+	assert.Equal(http.StatusAccepted, res.Code)
+	service.AssertExpectations(t)
+}
+
+func Test_WebhookReplay_should_return_conflict_when_the_delivery_is_queued(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.WebhookServiceMock)
+	service.On("Replay", "w1", "d1", "teste1@teste.com.br").Return(webhook.ErrDeliveryQueued)
+	handler := Handler{WebhookService: service}
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.WebhookReplay)(res, replayRequest("w1", "d1"))
+
+	assert.Equal(http.StatusConflict, res.Code)
+	assert.Contains(res.Body.String(), `"code":"delivery_already_queued"`)
+}
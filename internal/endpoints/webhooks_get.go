@@ -0,0 +1,22 @@
+package endpoints
+
+import (
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) WebhookGet(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	email := r.Context().Value("email").(string)
+	webhooks, err := h.WebhookService.Get(r.Context(), email)
+	return webhooks, 200, err
+}
+
+func (h *Handler) WebhookGetById(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "webhook_id", id)
+	email := r.Context().Value("email").(string)
+	webhook, err := h.WebhookService.GetBy(r.Context(), id, email)
+	return webhook, 200, err
+}
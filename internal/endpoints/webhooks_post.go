@@ -0,0 +1,23 @@
+package endpoints
+
+import (
+	"emailn/internal/contract"
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// WebhookPost creates a webhook, its response is the only one carrying the
+// secret.
+func (h *Handler) WebhookPost(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	var request contract.NewWebhook
+	render.DecodeJSON(r.Body, &request)
+	request.CreatedBy = r.Context().Value("email").(string)
+	webhook, err := h.WebhookService.Create(r.Context(), request)
+	if err != nil {
+		return nil, 0, err
+	}
+	logging.With(r.Context(), "webhook_id", webhook.ID)
+	return webhook, 201, nil
+}
@@ -0,0 +1,52 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"emailn/internal/contract"
+	"emailn/internal/domain/webhook"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internalmock "emailn/internal/test/internal-mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_WebhookPost_should_create_the_webhook_of_the_user(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.WebhookServiceMock)
+	service.On("Create", mock.MatchedBy(func(request contract.NewWebhook) bool {
+		return request.URL == "https://crm.example.com/hooks" && request.CreatedBy == "teste1@teste.com.br"
+	})).Return(&contract.WebhookResponse{ID: "w1", Secret: "s3cr3t"}, nil)
+	handler := Handler{WebhookService: service}
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(contract.NewWebhook{URL: "https://crm.example.com/hooks", Events: []string{webhook.CampaignStarted}})
+	req, _ := http.NewRequest("POST", "/", &buf)
+	req = req.WithContext(context.WithValue(req.Context(), "email", "teste1@teste.com.br"))
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.WebhookPost)(res, req)
+
+	assert.Equal(http.StatusCreated, res.Code)
+	assert.Contains(res.Body.String(), `"Secret":"s3cr3t"`)
+	service.AssertExpectations(t)
+}
+
+func Test_WebhookPost_should_return_not_implemented_without_the_job_queue(t *testing.T) {
+	assert := assert.New(t)
+	service := new(internalmock.WebhookServiceMock)
+	service.On("Create", mock.Anything).Return(nil, webhook.ErrUnavailable)
+	handler := Handler{WebhookService: service}
+	req, _ := http.NewRequest("POST", "/", bytes.NewBufferString(`{}`))
+	req = req.WithContext(context.WithValue(req.Context(), "email", "teste1@teste.com.br"))
+	res := httptest.NewRecorder()
+
+	HandlerError(handler.WebhookPost)(res, req)
+
+	assert.Equal(http.StatusNotImplemented, res.Code)
+	assert.Contains(res.Body.String(), `"code":"webhooks_unavailable"`)
+}
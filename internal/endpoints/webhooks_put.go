@@ -0,0 +1,22 @@
+package endpoints
+
+import (
+	"emailn/internal/contract"
+	"emailn/internal/logging"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// WebhookPut replaces the URL and the events of the webhook, the secret is
+// kept unless a new one is sent.
+func (h *Handler) WebhookPut(w http.ResponseWriter, r *http.Request) (interface{}, int, error) {
+	id := chi.URLParam(r, "id")
+	logging.With(r.Context(), "webhook_id", id)
+	var request contract.NewWebhook
+	render.DecodeJSON(r.Body, &request)
+	request.CreatedBy = r.Context().Value("email").(string)
+	webhook, err := h.WebhookService.Update(r.Context(), id, request)
+	return webhook, 200, err
+}
@@ -0,0 +1,124 @@
+package bounce
+
+import (
+	"bufio"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/infrastructure/mail"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+)
+
+var ErrNotDSN = errors.New("message is not a delivery status notification")
+
+// headers of the notification that may carry the VERP address it was sent to
+var verpHeaders = []string{"X-Original-To", "Delivered-To", "Envelope-To", "To"}
+
+// Parse reads a delivery status notification (RFC 3464) and returns one
+// bounce per recipient it reports. Each bounce is matched to its contact
+// through the VERP address of bounceAddress the notification was delivered
+// to or, failing that, through the Message-ID of the returned original
+// message.
+func Parse(r io.Reader, bounceAddress string) ([]campaign.Bounce, error) {
+	msg, err := netmail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "delivery-status") {
+		return nil, ErrNotDSN
+	}
+
+	contactID := ""
+	for _, header := range verpHeaders {
+		for _, address := range msg.Header[header] {
+			if id := mail.ContactIDFromVerp(bounceAddress, address); id != "" && contactID == "" {
+				contactID = id
+			}
+		}
+	}
+
+	var bounces []campaign.Bounce
+	messageID := ""
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/delivery-status":
+			bounces, err = parseDeliveryStatus(part)
+			if err != nil {
+				return nil, err
+			}
+		case "message/rfc822", "text/rfc822-headers":
+			header, err := textproto.NewReader(bufio.NewReader(part)).ReadMIMEHeader()
+			if err != nil && len(header) == 0 {
+				continue
+			}
+			messageID = header.Get("Message-Id")
+		}
+	}
+
+	if bounces == nil {
+		return nil, ErrNotDSN
+	}
+	if contactID == "" {
+		contactID = mail.ContactIDFromMessageID(messageID)
+	}
+	for i := range bounces {
+		bounces[i].ContactID = contactID
+	}
+	return bounces, nil
+}
+
+// parseDeliveryStatus reads the per-message fields followed by one block of
+// per-recipient fields for every recipient, each block ending in a blank line.
+func parseDeliveryStatus(r io.Reader) ([]campaign.Bounce, error) {
+	reader := textproto.NewReader(bufio.NewReader(r))
+	if _, err := reader.ReadMIMEHeader(); err != nil {
+		return nil, err
+	}
+
+	var bounces []campaign.Bounce
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			bounces = append(bounces, campaign.Bounce{
+				Recipient:  addressOf(fields.Get("Final-Recipient")),
+				Action:     strings.ToLower(strings.TrimSpace(fields.Get("Action"))),
+				Status:     strings.TrimSpace(fields.Get("Status")),
+				Diagnostic: valueOf(fields.Get("Diagnostic-Code")),
+			})
+		}
+		if err == io.EOF {
+			return bounces, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addressOf strips the address type of a recipient field ("rfc822; a@b.c").
+func addressOf(field string) string {
+	return strings.Trim(valueOf(field), "<>")
+}
+
+func valueOf(field string) string {
+	if i := strings.Index(field, ";"); i >= 0 {
+		field = field[i+1:]
+	}
+	return strings.TrimSpace(field)
+}
@@ -0,0 +1,63 @@
+package bounce
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bounceAddress = "bounces@emailn.com"
+
+func Test_Parse_matches_contact_by_verp_address(t *testing.T) {
+	assert := assert.New(t)
+	file, _ := os.Open("testdata/hard_bounce.eml")
+	defer file.Close()
+
+	bounces, err := Parse(file, bounceAddress)
+
+	assert.Nil(err)
+	assert.Len(bounces, 1)
+	assert.Equal("cp1bounce000000000000", bounces[0].ContactID)
+	assert.Equal("nobody@test.com", bounces[0].Recipient)
+	assert.Equal("failed", bounces[0].Action)
+	assert.Equal("5.1.1", bounces[0].Status)
+	assert.True(bounces[0].Failed())
+	assert.True(bounces[0].Hard())
+	assert.Contains(bounces[0].Diagnostic, "User unknown")
+}
+
+func Test_Parse_matches_contact_by_message_id(t *testing.T) {
+	assert := assert.New(t)
+	file, _ := os.Open("testdata/message_id_only.eml")
+	defer file.Close()
+
+	bounces, err := Parse(file, bounceAddress)
+
+	assert.Nil(err)
+	assert.Len(bounces, 1)
+	assert.Equal("cp1slow0000000000000", bounces[0].ContactID)
+	assert.False(bounces[0].Failed())
+}
+
+func Test_Parse_ignores_plus_addresses_other_than_the_bounce_address(t *testing.T) {
+	assert := assert.New(t)
+	fixture, _ := os.ReadFile("testdata/message_id_only.eml")
+	message := strings.Replace(string(fixture), "To: campaigns@emailn.com", "To: campaigns+news@emailn.com", 1)
+
+	bounces, err := Parse(strings.NewReader(message), bounceAddress)
+
+	assert.Nil(err)
+	assert.Len(bounces, 1)
+	assert.Equal("cp1slow0000000000000", bounces[0].ContactID)
+}
+
+func Test_Parse_returns_ErrNotDSN_for_regular_messages(t *testing.T) {
+	assert := assert.New(t)
+	message := "From: a@test.com\r\nTo: b@test.com\r\nContent-Type: text/plain\r\n\r\nhello\r\n"
+
+	_, err := Parse(strings.NewReader(message), bounceAddress)
+
+	assert.Equal(ErrNotDSN, err)
+}
@@ -0,0 +1,99 @@
+package bounce
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Maildir processes the delivery status notifications delivered to a local
+// Maildir. Processed messages are moved from new/ to cur/ so they are read
+// only once; messages that fail with an internal error stay in new/ and are
+// retried on the next poll.
+type Maildir struct {
+	Dir     string
+	Service campaign.Service
+	// BounceAddress is the envelope sender of the campaigns, see Parse.
+	BounceAddress string
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Run polls the Maildir every interval until ctx is done.
+func (m *Maildir) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Poll(ctx); err != nil {
+			m.logger().Error("polling bounce maildir", "dir", m.Dir, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll processes the messages in new/, it stops early when ctx is done.
+func (m *Maildir) Poll(ctx context.Context) error {
+	entries, err := os.ReadDir(filepath.Join(m.Dir, "new"))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(m.Dir, "new", entry.Name())
+		if err := m.process(ctx, path); err != nil {
+			m.logger().Error("processing bounce", "file", entry.Name(), "error", err)
+			continue
+		}
+		err = os.Rename(path, filepath.Join(m.Dir, "cur", entry.Name()+":2,S"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Maildir) process(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// anything that is not a readable notification is skipped, not retried
+	bounces, err := Parse(file, m.BounceAddress)
+	if err != nil {
+		m.logger().Warn("skipping message that is not a bounce", "file", filepath.Base(path), "error", err)
+		return nil
+	}
+
+	for _, bounce := range bounces {
+		err = m.Service.ProcessBounce(ctx, bounce)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Maildir) logger() *slog.Logger {
+	if m.Logger == nil {
+		return slog.Default()
+	}
+	return m.Logger
+}
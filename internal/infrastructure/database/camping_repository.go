@@ -1,49 +1,267 @@
 package database
 
 import (
+	"context"
 	"emailn/internal/domain/campaign"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CampaignRepository struct {
 	Db *gorm.DB
-	// campaigns []campaign.Campaign
+	// Timeout bounds every operation, 0 leaves it to the context.
+	Timeout time.Duration
 }
 
-func (c *CampaignRepository) Create(campaign *campaign.Campaign) error {
-	// c.campaigns = append(c.campaigns, *campaign)
-	tx := c.Db.Create(campaign)
+func (c *CampaignRepository) Create(ctx context.Context, campaign *campaign.Campaign) error {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	tx := db.Create(campaign)
 	return tx.Error
 }
 
-func (c *CampaignRepository) Update(campaign *campaign.Campaign) error {
-	// c.campaigns = append(c.campaigns, *campaign)
-	tx := c.Db.Save(campaign)
-	return tx.Error
+func (c *CampaignRepository) Update(ctx context.Context, changed *campaign.Campaign) error {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		updated := *changed
+		updated.Version++
+		// only over the version read, another update in between made it stale
+		result := tx.Model(&updated).Where("version = ?", changed.Version).
+			Select("*").Omit(clause.Associations).Updates(&updated)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return campaign.ErrVersionConflict
+		}
+		contacts := changed.ChangedContacts()
+		for _, contact := range contacts {
+			contact.CampaignId = changed.ID
+		}
+		return saveContacts(tx, contacts)
+	})
+	if err != nil {
+		return err
+	}
+	changed.Version++
+	for _, contact := range changed.ChangedContacts() {
+		contact.MarkSaved()
+	}
+	return nil
 }
 
-func (c *CampaignRepository) Get() ([]campaign.Campaign, error) {
+func (c *CampaignRepository) Get(ctx context.Context) ([]campaign.Campaign, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
 	var campaigns []campaign.Campaign
-	tx := c.Db.Find(&campaigns)
+	tx := db.Where("deleted_at IS NULL").Find(&campaigns)
 	return campaigns, tx.Error
 }
 
-func (c *CampaignRepository) GetBy(id string) (*campaign.Campaign, error) {
+func (c *CampaignRepository) GetBy(ctx context.Context, id string) (*campaign.Campaign, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var campaign campaign.Campaign
+	tx := db.Preload("Contacts").First(&campaign, "id = ? AND deleted_at IS NULL", id)
+	return &campaign, tx.Error
+}
+
+func (c *CampaignRepository) GetDeletedBy(ctx context.Context, id string) (*campaign.Campaign, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
 	var campaign campaign.Campaign
-	tx := c.Db.Preload("Contacts").First(&campaign, "id = ?", id)
-	// if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
-	// 	return nil, nil
-	// }
+	tx := db.Preload("Contacts").First(&campaign, "id = ? AND deleted_at IS NOT NULL", id)
 	return &campaign, tx.Error
 }
 
-func (c *CampaignRepository) Delete(campaign *campaign.Campaign) error {
-	// c.campaigns = append(c.campaigns, *campaign)
-	// for i, _ := range campaign.Contacts {
-	// 	c.Db.Delete(campaign.Contacts[i])
-	// }
+func (c *CampaignRepository) GetByStatus(ctx context.Context, status string) ([]campaign.Campaign, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var campaigns []campaign.Campaign
+	tx := db.Where("status = ? AND deleted_at IS NULL", status).Find(&campaigns)
+	return campaigns, tx.Error
+}
+
+func (c *CampaignRepository) GetStats(ctx context.Context, id string) (*campaign.Stats, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var saved campaign.Campaign
+	tx := db.Select("id", "status", "started_on", "finished_on").First(&saved, "id = ? AND deleted_at IS NULL", id)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var stats campaign.Stats
+	// one pass over the campaign_id index instead of loading every contact
+	tx = db.Model(&campaign.Contact{}).
+		Select(`COUNT(*) AS total,
+			COUNT(sent_on) AS sent,
+			COUNT(CASE WHEN status = ? THEN 1 END) AS failed,
+			COUNT(CASE WHEN status = ? THEN 1 END) AS bounced,
+			COUNT(CASE WHEN status = ? THEN 1 END) AS suppressed,
+			COUNT(opened_on) AS opened,
+			COUNT(clicked_on) AS clicked,
+			COUNT(unsubscribed_on) AS unsubscribed`,
+			campaign.ContactFailed, campaign.ContactBounced, campaign.ContactSuppressed).
+		Where("campaign_id = ?", id).
+		Scan(&stats)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	stats.CampaignID = saved.ID
+	stats.Status = saved.Status
+	stats.StartedOn = saved.StartedOn
+	stats.FinishedOn = saved.FinishedOn
+	return &stats, nil
+}
+
+func (c *CampaignRepository) Delete(ctx context.Context, deleted *campaign.Campaign) error {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	tx := db.Model(&campaign.Campaign{ID: deleted.ID}).Where("version = ?", deleted.Version).
+		Updates(map[string]any{
+			"status":     deleted.Status,
+			"deleted_at": deleted.DeletedOn,
+			"version":    deleted.Version + 1,
+		})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return campaign.ErrVersionConflict
+	}
+	deleted.Version++
+	return nil
+}
+
+func (c *CampaignRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// the rows stay locked so a concurrent restore waits for the purge
+		var ids []string
+		err := tx.Model(&campaign.Campaign{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at < ?", deletedBefore).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		err = tx.Where("campaign_id IN ?", ids).Delete(&campaign.Contact{}).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", ids).Delete(&campaign.Campaign{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+func (c *CampaignRepository) GetOwner(ctx context.Context, id string) (string, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var owner campaign.Campaign
+	tx := db.Select("created_by").First(&owner, "id = ?", id)
+	return owner.CreatedBy, tx.Error
+}
+
+func (c *CampaignRepository) GetContactBy(ctx context.Context, id string) (*campaign.Contact, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var contact campaign.Contact
+	tx := db.First(&contact, "id = ?", id)
+	return &contact, tx.Error
+}
 
-	tx := c.Db.Select("Contacts").Delete(campaign)
+func (c *CampaignRepository) UpdateContact(ctx context.Context, contact *campaign.Contact) error {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	tx := db.Save(contact)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	contact.MarkSaved()
+	return nil
+}
+
+func (c *CampaignRepository) UpdateContacts(ctx context.Context, contacts []*campaign.Contact) error {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	if err := saveContacts(db, contacts); err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		contact.MarkSaved()
+	}
+	return nil
+}
+
+// saveContacts upserts the contacts contactBatchSize at a time, Save does not
+// use the CreateBatchSize of the config.
+func saveContacts(db *gorm.DB, contacts []*campaign.Contact) error {
+	for start := 0; start < len(contacts); start += contactBatchSize {
+		end := min(start+contactBatchSize, len(contacts))
+		if err := db.Save(contacts[start:end]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CampaignRepository) CreateSuppression(ctx context.Context, suppression *campaign.Suppression) error {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	tx := db.Clauses(clause.OnConflict{DoNothing: true}).Create(suppression)
 	return tx.Error
 }
+
+func (c *CampaignRepository) GetSuppressedEmails(ctx context.Context, campaignID string) ([]string, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var emails []string
+	tx := db.Model(&campaign.Contact{}).
+		Joins("JOIN suppressions ON suppressions.email = LOWER(contacts.email)").
+		Where("contacts.campaign_id = ?", campaignID).
+		Pluck("contacts.email", &emails)
+	return emails, tx.Error
+}
+
+// CountByStatus and CountPendingContacts feed the metrics collector.
+func (c *CampaignRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	tx := db.Model(&campaign.Campaign{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (c *CampaignRepository) CountPendingContacts(ctx context.Context) (int64, error) {
+	db, cancel := c.db(ctx)
+	defer cancel()
+	var count int64
+	tx := db.Model(&campaign.Contact{}).
+		Joins("JOIN campaigns ON campaigns.id = contacts.campaign_id").
+		Where("campaigns.status = ? AND contacts.status IN ?", campaign.Started, []string{campaign.ContactPending, campaign.ContactSending}).
+		Count(&count)
+	return count, tx.Error
+}
+
+func (c *CampaignRepository) db(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	return withTimeout(ctx, c.Db, c.Timeout)
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
+	"emailn/internal/infrastructure/repositorytest"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// testDatabases returns the backends to run the contract against: always a
+// SQLite file, and Postgres when TEST_DATABASE holds its DSN.
+func testDatabases() map[string]func(t *testing.T) *gorm.DB {
+	databases := map[string]func(t *testing.T) *gorm.DB{
+		"sqlite": func(t *testing.T) *gorm.DB {
+			return openTestDb(t, sqliteScheme+filepath.Join(t.TempDir(), "emailn.db"))
+		},
+	}
+	if dsn := os.Getenv("TEST_DATABASE"); dsn != "" {
+		databases["postgres"] = func(t *testing.T) *gorm.DB {
+			db := openTestDb(t, dsn)
+			t.Cleanup(func() {
+				db.Exec("TRUNCATE contacts, campaigns, suppressions, idempotency_keys, jobs, outbox_messages, webhook_deliveries, webhook_subscriptions")
+			})
+			return db
+		}
+	}
+	return databases
+}
+
+func openTestDb(t *testing.T, dsn string) *gorm.DB {
+	db := NewDb(dsn, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func Test_CampaignRepository_contract(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.RunCampaignRepository(t, func(t *testing.T) repositorytest.CampaignRepository {
+				return &CampaignRepository{Db: open(t)}
+			})
+		})
+	}
+}
+
+func Test_IdempotencyRepository_contract(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.RunIdempotencyRepository(t, func(t *testing.T) idempotency.Repository {
+				return &IdempotencyRepository{Db: open(t)}
+			})
+		})
+	}
+}
+
+func Test_JobRepository_contract(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.RunJobRepository(t, func(t *testing.T) job.Repository {
+				return &JobRepository{Db: open(t)}
+			})
+		})
+	}
+}
+
+func Test_OutboxRepository_contract(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.RunOutboxRepository(t, func(t *testing.T) outbox.Repository {
+				return &OutboxRepository{Db: open(t)}
+			})
+		})
+	}
+}
+
+func Test_WebhookRepository_contract(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.RunWebhookRepository(t, func(t *testing.T) webhook.Repository {
+				return &WebhookRepository{Db: open(t)}
+			})
+		})
+	}
+}
+
+func Test_CampaignRepository_should_stop_at_the_Timeout(t *testing.T) {
+	db := openTestDb(t, sqliteScheme+filepath.Join(t.TempDir(), "emailn.db"))
+	repository := &CampaignRepository{Db: db, Timeout: time.Nanosecond}
+
+	_, err := repository.GetByStatus(context.Background(), campaign.Started)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_CampaignRepository_should_stop_when_the_context_is_cancelled(t *testing.T) {
+	db := openTestDb(t, sqliteScheme+filepath.Join(t.TempDir(), "emailn.db"))
+	repository := &CampaignRepository{Db: db, Timeout: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repository.GetByStatus(ctx, campaign.Started)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
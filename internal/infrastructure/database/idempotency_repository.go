@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/idempotency"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository struct {
+	Db *gorm.DB
+	// Timeout bounds every operation, 0 leaves it to the context.
+	Timeout time.Duration
+}
+
+func (i *IdempotencyRepository) GetBy(ctx context.Context, key string, createdBy string) (*idempotency.Record, error) {
+	db, cancel := i.db(ctx)
+	defer cancel()
+	var record idempotency.Record
+	tx := db.First(&record, "key = ? AND created_by = ?", key, createdBy)
+	return &record, tx.Error
+}
+
+func (i *IdempotencyRepository) Create(ctx context.Context, record *idempotency.Record) error {
+	db, cancel := i.db(ctx)
+	defer cancel()
+	tx := db.Create(record)
+	return tx.Error
+}
+
+func (i *IdempotencyRepository) Update(ctx context.Context, record *idempotency.Record) error {
+	db, cancel := i.db(ctx)
+	defer cancel()
+	tx := db.Save(record)
+	return tx.Error
+}
+
+func (i *IdempotencyRepository) Delete(ctx context.Context, record *idempotency.Record) error {
+	db, cancel := i.db(ctx)
+	defer cancel()
+	tx := db.Delete(record)
+	return tx.Error
+}
+
+func (i *IdempotencyRepository) Purge(ctx context.Context, createdBefore time.Time) (int64, error) {
+	db, cancel := i.db(ctx)
+	defer cancel()
+	tx := db.Where("created_on < ?", createdBefore).Delete(&idempotency.Record{})
+	return tx.RowsAffected, tx.Error
+}
+
+func (i *IdempotencyRepository) db(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	return withTimeout(ctx, i.Db, i.Timeout)
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/job"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	Db *gorm.DB
+	// Timeout bounds every operation, 0 leaves it to the context.
+	Timeout time.Duration
+}
+
+func (j *JobRepository) Create(ctx context.Context, created *job.Job) error {
+	db, cancel := j.db(ctx)
+	defer cancel()
+	// in a savepoint, a duplicated key must not abort the transaction of a
+	// unit of work, Postgres refuses any other statement of it then
+	return db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(created).Error
+	})
+}
+
+func (j *JobRepository) GetBy(ctx context.Context, id string) (*job.Job, error) {
+	db, cancel := j.db(ctx)
+	defer cancel()
+	var saved job.Job
+	tx := db.First(&saved, "id = ?", id)
+	return &saved, tx.Error
+}
+
+func (j *JobRepository) Claim(ctx context.Context, kinds []string, worker string, now time.Time, until time.Time) (*job.Job, error) {
+	db, cancel := j.db(ctx)
+	defer cancel()
+
+	var claimed job.Job
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED lets every replica claim a different job without waiting,
+		// SQLite ignores the locking clause and serializes the transactions
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("kind IN ? AND status IN ? AND run_at <= ?", kinds, []string{job.Queued, job.Running}, now).
+			Where("locked_until IS NULL OR locked_until < ?", now).
+			Order("run_at").
+			First(&claimed).Error
+		if err != nil {
+			return err
+		}
+
+		// the lease is only taken when still free, in case another worker won
+		result := tx.Model(&job.Job{}).
+			Where("id = ? AND (locked_until IS NULL OR locked_until < ?)", claimed.ID, now).
+			Updates(map[string]any{
+				"status":       job.Running,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"locked_by":    worker,
+				"locked_until": until,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claimed.Status = job.Running
+	claimed.Attempts++
+	claimed.LockedBy = worker
+	claimed.LockedUntil = &until
+	return &claimed, nil
+}
+
+func (j *JobRepository) Extend(ctx context.Context, leased *job.Job, until time.Time) error {
+	db, cancel := j.db(ctx)
+	defer cancel()
+	result := db.Model(&job.Job{}).
+		Where("id = ? AND locked_by = ?", leased.ID, leased.LockedBy).
+		Update("locked_until", until)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return job.ErrLeaseLost
+	}
+	leased.LockedUntil = &until
+	return nil
+}
+
+func (j *JobRepository) Finish(ctx context.Context, finished *job.Job) error {
+	db, cancel := j.db(ctx)
+	defer cancel()
+
+	held := db.Where("id = ? AND locked_by = ?", finished.ID, finished.LockedBy)
+	var result *gorm.DB
+	if finished.Status == job.Done {
+		result = held.Delete(&job.Job{})
+	} else {
+		result = held.Model(&job.Job{}).Updates(map[string]any{
+			"status":       finished.Status,
+			"attempts":     finished.Attempts,
+			"run_at":       finished.RunAt,
+			"last_error":   finished.LastError,
+			"finished_on":  finished.FinishedOn,
+			"locked_by":    "",
+			"locked_until": nil,
+		})
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return job.ErrLeaseLost
+	}
+	finished.LockedBy = ""
+	finished.LockedUntil = nil
+	return nil
+}
+
+func (j *JobRepository) db(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	return withTimeout(ctx, j.Db, j.Timeout)
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_loadMigrations_should_order_by_version(t *testing.T) {
+	assert := assert.New(t)
+	dir := fstest.MapFS{
+		"0010_add_index.up.sql":    {Data: []byte("CREATE INDEX")},
+		"0002_add_column.up.sql":   {Data: []byte("ALTER TABLE")},
+		"0002_add_column.down.sql": {Data: []byte("ALTER TABLE DROP")},
+	}
+
+	migrations, err := loadMigrations(dir)
+
+	assert.Nil(err)
+	assert.Equal([]Migration{
+		{Version: 2, Name: "add_column", Up: "ALTER TABLE", Down: "ALTER TABLE DROP"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX"},
+	}, migrations)
+}
+
+func Test_loadMigrations_should_reject_migration_without_up_file(t *testing.T) {
+	assert := assert.New(t)
+	dir := fstest.MapFS{"0003_drop.down.sql": {Data: []byte("DROP")}}
+
+	_, err := loadMigrations(dir)
+
+	assert.EqualError(err, "migration 3_drop has no up file")
+}
+
+func Test_loadMigrations_should_reject_unexpected_files(t *testing.T) {
+	assert := assert.New(t)
+	dir := fstest.MapFS{"initial.sql": {Data: []byte("CREATE TABLE")}}
+
+	_, err := loadMigrations(dir)
+
+	assert.EqualError(err, `unexpected migration file "initial.sql"`)
+}
+
+func Test_Pending_should_report_every_migration_without_creating_the_table(t *testing.T) {
+	assert := assert.New(t)
+	db := NewDb(sqliteScheme+filepath.Join(t.TempDir(), "emailn.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	migrator, _ := NewMigrator(db)
+
+	pending, err := migrator.Pending(context.Background())
+
+	assert.Nil(err)
+	assert.Len(pending, len(migrator.Migrations))
+	assert.False(db.Migrator().HasTable("schema_migrations"))
+}
+
+func Test_Pending_should_leave_out_the_applied_migrations(t *testing.T) {
+	assert := assert.New(t)
+	db := NewDb(sqliteScheme+filepath.Join(t.TempDir(), "emailn.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	migrator, _ := NewMigrator(db)
+	migrator.Up(context.Background())
+
+	pending, err := migrator.Pending(context.Background())
+
+	assert.Nil(err)
+	assert.Empty(pending)
+}
+
+// the campaigns and contacts of the baseline release, the first AutoMigrate
+// made
+type baselineCampaign struct {
+	ID        string `gorm:"size:50"`
+	Name      string `gorm:"size:100"`
+	CreatedOn time.Time
+	Content   string            `gorm:"size:1024"`
+	Contacts  []baselineContact `gorm:"foreignKey:CampaignId"`
+	Status    string            `gorm:"size:20"`
+	CreatedBy string            `gorm:"size:50"`
+}
+
+func (baselineCampaign) TableName() string { return "campaigns" }
+
+type baselineContact struct {
+	ID         string `gorm:"size:50"`
+	Email      string `gorm:"size:100"`
+	CampaignId string `gorm:"size:50"`
+}
+
+func (baselineContact) TableName() string { return "contacts" }
+
+func Test_Up_should_bring_a_baseline_AutoMigrate_database_up_to_date(t *testing.T) {
+	// SQLite came after the versioned migrations, only Postgres databases
+	// were made by AutoMigrate
+	dsn := os.Getenv("TEST_DATABASE")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE is not set")
+	}
+	assert := assert.New(t)
+	ctx := context.Background()
+	db := NewDb(dsn, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	dropAll := func() {
+		db.Exec(`DROP TABLE IF EXISTS schema_migrations, webhook_deliveries, webhook_subscriptions,
+			outbox_messages, jobs, idempotency_keys, suppressions, contacts, campaigns CASCADE`)
+	}
+	dropAll()
+	t.Cleanup(func() {
+		// the other tests expect the migrated schema
+		dropAll()
+		migrator, _ := NewMigrator(db)
+		migrator.Up(ctx)
+	})
+	if err := db.AutoMigrate(&baselineCampaign{}, &baselineContact{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&baselineCampaign{ID: "done1", Name: "Baseline", CreatedOn: time.Now(), Content: "body HI!",
+		Status: campaign.Done, CreatedBy: "teste@test.com.br", Contacts: []baselineContact{{ID: "c1", Email: "um@test.com"}}})
+	db.Create(&baselineCampaign{ID: "pending1", Name: "Baseline", CreatedOn: time.Now(), Content: "body HI!",
+		Status: campaign.Peding, CreatedBy: "teste@test.com.br", Contacts: []baselineContact{{ID: "c2", Email: "dois@test.com"}}})
+	migrator, _ := NewMigrator(db)
+
+	_, err := migrator.Up(ctx)
+
+	assert.Nil(err)
+	repository := &CampaignRepository{Db: db}
+	done, err := repository.GetBy(ctx, "done1")
+	assert.Nil(err)
+	assert.Equal(campaign.ContactSent, done.Contacts[0].Status)
+	pending, err := repository.GetBy(ctx, "pending1")
+	assert.Nil(err)
+	assert.Equal(campaign.ContactPending, pending.Contacts[0].Status)
+	pending.Start()
+	pending.Contacts[0].MarkSending(pending.SendRunID)
+	assert.Nil(repository.Update(ctx, pending))
+	stats, err := repository.GetStats(ctx, "pending1")
+	assert.Nil(err)
+	assert.Equal(campaign.Started, stats.Status)
+}
+
+func Test_embedded_migrations_should_load_with_same_versions_for_every_dialect(t *testing.T) {
+	assert := assert.New(t)
+	postgresDir, _ := fs.Sub(migrationFiles, "migrations/postgres")
+	sqliteDir, _ := fs.Sub(migrationFiles, "migrations/sqlite")
+
+	postgres, err := loadMigrations(postgresDir)
+	assert.Nil(err)
+	sqlite, err := loadMigrations(sqliteDir)
+	assert.Nil(err)
+
+	assert.Equal(len(postgres), len(sqlite))
+	for i, migration := range postgres {
+		assert.NotEmpty(migration.Down, "migration %d has no down file", migration.Version)
+		assert.Equal(migration.Version, sqlite[i].Version)
+		assert.Equal(migration.Name, sqlite[i].Name)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS suppressions;
+DROP TABLE IF EXISTS contacts;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,72 @@
+-- The schema gorm AutoMigrate built before versioned migrations. A database
+-- AutoMigrate made with an older release has the tables but may miss columns
+-- added since the first one, the ALTERs below add them and fill the rows
+-- already there.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id varchar(50) PRIMARY KEY,
+    name varchar(100),
+    created_on timestamptz,
+    content varchar(1024),
+    status varchar(20),
+    created_by varchar(50),
+    started_on timestamptz,
+    finished_on timestamptz,
+    send_run_id varchar(50),
+    max_per_second decimal
+);
+
+CREATE TABLE IF NOT EXISTS contacts (
+    id varchar(50) PRIMARY KEY,
+    email varchar(100),
+    campaign_id varchar(50) CONSTRAINT fk_campaigns_contacts REFERENCES campaigns (id),
+    status varchar(20),
+    provider_message_id varchar(255),
+    send_run_id varchar(50),
+    sent_on timestamptz,
+    bounced_on timestamptz,
+    opened_on timestamptz,
+    clicked_on timestamptz,
+    unsubscribed_on timestamptz
+);
+
+ALTER TABLE campaigns
+    ADD COLUMN IF NOT EXISTS started_on timestamptz,
+    ADD COLUMN IF NOT EXISTS finished_on timestamptz,
+    ADD COLUMN IF NOT EXISTS send_run_id varchar(50),
+    ADD COLUMN IF NOT EXISTS max_per_second decimal;
+UPDATE campaigns SET send_run_id = '' WHERE send_run_id IS NULL;
+UPDATE campaigns SET max_per_second = 0 WHERE max_per_second IS NULL;
+
+ALTER TABLE contacts
+    ADD COLUMN IF NOT EXISTS status varchar(20),
+    ADD COLUMN IF NOT EXISTS provider_message_id varchar(255),
+    ADD COLUMN IF NOT EXISTS send_run_id varchar(50),
+    ADD COLUMN IF NOT EXISTS sent_on timestamptz,
+    ADD COLUMN IF NOT EXISTS bounced_on timestamptz,
+    ADD COLUMN IF NOT EXISTS opened_on timestamptz,
+    ADD COLUMN IF NOT EXISTS clicked_on timestamptz,
+    ADD COLUMN IF NOT EXISTS unsubscribed_on timestamptz;
+-- contacts from before the statuses: the ones of a finished campaign were sent
+UPDATE contacts SET status = 'Sent'
+    WHERE status IS NULL AND campaign_id IN (SELECT id FROM campaigns WHERE status = 'Done');
+UPDATE contacts SET status = 'Pending' WHERE status IS NULL;
+UPDATE contacts SET provider_message_id = '' WHERE provider_message_id IS NULL;
+UPDATE contacts SET send_run_id = '' WHERE send_run_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_contacts_campaign_id ON contacts (campaign_id);
+CREATE INDEX IF NOT EXISTS idx_contacts_provider_message_id ON contacts (provider_message_id);
+
+CREATE TABLE IF NOT EXISTS suppressions (
+    email varchar(100) PRIMARY KEY,
+    reason varchar(255),
+    created_on timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key varchar(255),
+    created_by varchar(50),
+    request_hash varchar(64),
+    status_code bigint,
+    response text,
+    created_on timestamptz,
+    PRIMARY KEY (key, created_by)
+);
@@ -0,0 +1,3 @@
+-- Deleted campaigns are kept until the purge removes them.
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_campaigns_deleted_at ON campaigns (deleted_at);
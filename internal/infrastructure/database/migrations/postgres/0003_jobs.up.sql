@@ -0,0 +1,19 @@
+-- Durable queue of the background work, see job.Queue.
+CREATE TABLE IF NOT EXISTS jobs (
+    id varchar(50) PRIMARY KEY,
+    kind varchar(50) NOT NULL,
+    key varchar(255),
+    payload text,
+    status varchar(20) NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    max_attempts integer NOT NULL DEFAULT 1,
+    run_at timestamptz NOT NULL,
+    locked_by varchar(100),
+    locked_until timestamptz,
+    last_error text,
+    created_on timestamptz,
+    finished_on timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs (status, run_at);
+-- one Queued or Running job per key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_active_key ON jobs (key) WHERE key <> '' AND status IN ('Queued', 'Running');
@@ -0,0 +1,9 @@
+-- Messages written with the changes they follow, see outbox.Relay.
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id varchar(50) PRIMARY KEY,
+    topic varchar(50) NOT NULL,
+    key varchar(255),
+    payload text,
+    created_on timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_created_on ON outbox_messages (created_on);
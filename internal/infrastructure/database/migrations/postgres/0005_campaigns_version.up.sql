@@ -0,0 +1,2 @@
+-- Optimistic concurrency of the campaign updates, see Campaign.Version.
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_outbox_messages_due;
+ALTER TABLE outbox_messages
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS last_error;
@@ -0,0 +1,9 @@
+-- Retries of the outbox messages failing to publish, see outbox.Relay.
+ALTER TABLE outbox_messages
+    ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'Pending',
+    ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz,
+    ADD COLUMN IF NOT EXISTS last_error text;
+UPDATE outbox_messages SET next_attempt_at = created_on WHERE next_attempt_at IS NULL;
+ALTER TABLE outbox_messages ALTER COLUMN next_attempt_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages (status, next_attempt_at);
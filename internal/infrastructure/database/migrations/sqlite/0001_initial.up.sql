@@ -0,0 +1,47 @@
+-- Same schema as postgres/0001_initial.up.sql with the SQLite types. SQLite
+-- came after the versioned migrations, it has no AutoMigrate database to
+-- bring up to date.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id varchar(50) PRIMARY KEY,
+    name varchar(100),
+    created_on datetime,
+    content varchar(1024),
+    status varchar(20),
+    created_by varchar(50),
+    started_on datetime,
+    finished_on datetime,
+    send_run_id varchar(50),
+    max_per_second real
+);
+
+CREATE TABLE IF NOT EXISTS contacts (
+    id varchar(50) PRIMARY KEY,
+    email varchar(100),
+    campaign_id varchar(50) CONSTRAINT fk_campaigns_contacts REFERENCES campaigns (id),
+    status varchar(20),
+    provider_message_id varchar(255),
+    send_run_id varchar(50),
+    sent_on datetime,
+    bounced_on datetime,
+    opened_on datetime,
+    clicked_on datetime,
+    unsubscribed_on datetime
+);
+CREATE INDEX IF NOT EXISTS idx_contacts_campaign_id ON contacts (campaign_id);
+CREATE INDEX IF NOT EXISTS idx_contacts_provider_message_id ON contacts (provider_message_id);
+
+CREATE TABLE IF NOT EXISTS suppressions (
+    email varchar(100) PRIMARY KEY,
+    reason varchar(255),
+    created_on datetime
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key varchar(255),
+    created_by varchar(50),
+    request_hash varchar(64),
+    status_code integer,
+    response text,
+    created_on datetime,
+    PRIMARY KEY (key, created_by)
+);
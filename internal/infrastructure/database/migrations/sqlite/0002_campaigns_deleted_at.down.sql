@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_campaigns_deleted_at;
+ALTER TABLE campaigns DROP COLUMN deleted_at;
@@ -0,0 +1,9 @@
+-- Same schema as postgres/0004_outbox.up.sql with the SQLite types.
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id varchar(50) PRIMARY KEY,
+    topic varchar(50) NOT NULL,
+    key varchar(255),
+    payload text,
+    created_on datetime NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_created_on ON outbox_messages (created_on);
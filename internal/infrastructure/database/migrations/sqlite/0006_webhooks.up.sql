@@ -0,0 +1,26 @@
+-- Same schema as postgres/0006_webhooks.up.sql with the SQLite types.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id varchar(50) PRIMARY KEY,
+    url varchar(2048) NOT NULL,
+    events text NOT NULL,
+    secret varchar(255) NOT NULL,
+    created_by varchar(50),
+    created_on datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_created_by ON webhook_subscriptions (created_by);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id varchar(50) PRIMARY KEY,
+    subscription_id varchar(50) NOT NULL REFERENCES webhook_subscriptions (id),
+    event_id varchar(50) NOT NULL,
+    event_type varchar(50) NOT NULL,
+    payload text,
+    status varchar(20) NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    response_status integer NOT NULL DEFAULT 0,
+    last_error text,
+    created_on datetime,
+    last_attempt_on datetime,
+    delivered_on datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_on);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_outbox_messages_due;
+ALTER TABLE outbox_messages DROP COLUMN status;
+ALTER TABLE outbox_messages DROP COLUMN attempts;
+ALTER TABLE outbox_messages DROP COLUMN next_attempt_at;
+ALTER TABLE outbox_messages DROP COLUMN last_error;
@@ -0,0 +1,8 @@
+-- Same schema as postgres/0007_outbox_attempts.up.sql with the SQLite types,
+-- which cannot add a NOT NULL column without a constant default.
+ALTER TABLE outbox_messages ADD COLUMN status varchar(20) NOT NULL DEFAULT 'Pending';
+ALTER TABLE outbox_messages ADD COLUMN attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE outbox_messages ADD COLUMN next_attempt_at datetime;
+ALTER TABLE outbox_messages ADD COLUMN last_error text;
+UPDATE outbox_messages SET next_attempt_at = created_on WHERE next_attempt_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages (status, next_attempt_at);
@@ -1,21 +1,68 @@
 package database
 
 import (
-	"emailn/internal/domain/campaign"
-	"os"
+	"context"
+	"emailn/internal/infrastructure/metrics"
+	"emailn/internal/logging"
+	"emailn/internal/tracing"
+	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func NewDb() *gorm.DB {
-	dsn := os.Getenv("DATABASE")
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// sqliteScheme prefixes the DSN of a SQLite database, sqlite://emailn.db
+// opens the file emailn.db. Any other DSN is a Postgres one.
+const sqliteScheme = "sqlite://"
+
+// contactBatchSize bounds the rows of one INSERT, a campaign's contacts in a
+// single statement go over the bind variable limit of SQLite and Postgres.
+const contactBatchSize = 500
+
+func NewDb(dsn string, logger *slog.Logger) *gorm.DB {
+	db, err := gorm.Open(dialector(dsn), &gorm.Config{
+		TranslateError:  true,
+		CreateBatchSize: contactBatchSize,
+		Logger:          &logging.GormLogger{Logger: logger, SlowThreshold: 200 * time.Millisecond},
+	})
 	if err != nil {
 		panic("fail to connect to database")
 	}
-
-	db.AutoMigrate(&campaign.Campaign{}, &campaign.Contact{})
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		panic("fail to register database metrics")
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		panic("fail to register database tracing")
+	}
 
 	return db
 }
+
+func dialector(dsn string) gorm.Dialector {
+	path, isSQLite := strings.CutPrefix(dsn, sqliteScheme)
+	if !isSQLite {
+		return postgres.Open(dsn)
+	}
+
+	// foreign keys are off by default in SQLite, and the busy timeout lets
+	// concurrent writers wait for the lock instead of failing
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return sqlite.Open(path + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+}
+
+// Ping checks the connection pool can still reach the database.
+func Ping(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
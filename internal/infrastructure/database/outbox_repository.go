@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/outbox"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	Db *gorm.DB
+	// Timeout bounds every operation, 0 leaves it to the context.
+	Timeout time.Duration
+}
+
+func (o *OutboxRepository) Create(ctx context.Context, message *outbox.Message) error {
+	db, cancel := o.db(ctx)
+	defer cancel()
+	return db.Create(message).Error
+}
+
+func (o *OutboxRepository) Next(ctx context.Context, now time.Time) (*outbox.Message, error) {
+	db, cancel := o.db(ctx)
+	defer cancel()
+	var message outbox.Message
+	// the lock only holds within a UnitOfWork, SQLite ignores it and
+	// serializes the transactions
+	tx := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", outbox.Pending, now).
+		Order("created_on, id").
+		First(&message)
+	return &message, tx.Error
+}
+
+func (o *OutboxRepository) Update(ctx context.Context, message *outbox.Message) error {
+	db, cancel := o.db(ctx)
+	defer cancel()
+	return db.Model(message).Select("status", "attempts", "next_attempt_at", "last_error").Updates(message).Error
+}
+
+func (o *OutboxRepository) Delete(ctx context.Context, message *outbox.Message) error {
+	db, cancel := o.db(ctx)
+	defer cancel()
+	return db.Delete(&outbox.Message{}, "id = ?", message.ID).Error
+}
+
+func (o *OutboxRepository) db(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	return withTimeout(ctx, o.Db, o.Timeout)
+}
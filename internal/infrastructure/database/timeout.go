@@ -0,0 +1,20 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// withTimeout binds db to ctx, cut off after timeout when it is set. The
+// deadline covers the whole repository operation, rows scanned included.
+// Within a UnitOfWork the operation runs in its transaction.
+func withTimeout(ctx context.Context, db *gorm.DB, timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	db = session(ctx, db)
+	if timeout <= 0 {
+		return db.WithContext(ctx), func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return db.WithContext(ctx), cancel
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// UnitOfWork runs its functions in a database transaction, carried by the
+// context to the repositories sharing its Db. A unit of work started inside
+// another one is a savepoint of it.
+type UnitOfWork struct {
+	Db *gorm.DB
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	db := u.Db
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		db = tx
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// session is the transaction of the unit of work ctx runs in, or db.
+func session(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func Test_UnitOfWork_should_commit_the_writes_of_every_repository_together(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := open(t)
+			campaigns := &CampaignRepository{Db: db}
+			messages := &OutboxRepository{Db: db}
+			created, _ := campaign.NewCampaign("Test Y", "body HI!", []string{"teste1@test.com"}, "teste@test.com.br")
+			campaigns.Create(context.Background(), created)
+			message, _ := outbox.New(campaign.SendJob, "", nil)
+
+			err := (&UnitOfWork{Db: db}).Do(context.Background(), func(ctx context.Context) error {
+				created.Start()
+				if err := campaigns.Update(ctx, created); err != nil {
+					return err
+				}
+				return messages.Create(ctx, message)
+			})
+
+			assert.Nil(err)
+			saved, _ := campaigns.GetBy(context.Background(), created.ID)
+			assert.Equal(campaign.Started, saved.Status)
+			next, err := messages.Next(context.Background(), time.Now())
+			assert.Nil(err)
+			assert.Equal(message.ID, next.ID)
+		})
+	}
+}
+
+func Test_UnitOfWork_should_roll_back_every_write_on_error(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := open(t)
+			campaigns := &CampaignRepository{Db: db}
+			messages := &OutboxRepository{Db: db}
+			created, _ := campaign.NewCampaign("Test Y", "body HI!", []string{"teste1@test.com"}, "teste@test.com.br")
+			campaigns.Create(context.Background(), created)
+			errFailed := errors.New("failed")
+
+			err := (&UnitOfWork{Db: db}).Do(context.Background(), func(ctx context.Context) error {
+				created.Start()
+				campaigns.Update(ctx, created)
+				message, _ := outbox.New(campaign.SendJob, "", nil)
+				messages.Create(ctx, message)
+				return errFailed
+			})
+
+			assert.ErrorIs(err, errFailed)
+			saved, _ := campaigns.GetBy(context.Background(), created.ID)
+			assert.Equal(campaign.Peding, saved.Status)
+			_, err = messages.Next(context.Background(), time.Now())
+			assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		})
+	}
+}
+
+func Test_Relay_should_queue_each_outbox_message_once(t *testing.T) {
+	for name, open := range testDatabases() {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := open(t)
+			jobs := &JobRepository{Db: db}
+			messages := &OutboxRepository{Db: db}
+			queue := &job.Queue{Repository: jobs, MaxAttempts: 1}
+			relay := &outbox.Relay{Repository: messages, Transactions: &UnitOfWork{Db: db}, Publisher: queue}
+			first, _ := outbox.New(campaign.SendJob, "campaign.send:1", map[string]string{"campaign_id": "1"})
+			again, _ := outbox.New(campaign.SendJob, "campaign.send:1", map[string]string{"campaign_id": "1"})
+			messages.Create(context.Background(), first)
+			messages.Create(context.Background(), again)
+
+			for i := 0; i < 2; i++ {
+				found, err := relay.RelayNext(context.Background())
+				assert.True(found)
+				assert.Nil(err)
+			}
+			found, _ := relay.RelayNext(context.Background())
+
+			assert.False(found)
+			now := time.Now().UTC()
+			claimed, err := jobs.Claim(context.Background(), []string{campaign.SendJob}, "worker1", now.Add(time.Second), now.Add(time.Minute))
+			assert.Nil(err)
+			assert.Equal("campaign.send:1", claimed.Key)
+			_, err = jobs.Claim(context.Background(), []string{campaign.SendJob}, "worker2", now.Add(time.Second), now.Add(time.Minute))
+			assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		})
+	}
+}
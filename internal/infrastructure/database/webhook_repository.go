@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"emailn/internal/domain/webhook"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	Db *gorm.DB
+	// Timeout bounds every operation, 0 leaves it to the context.
+	Timeout time.Duration
+}
+
+func (w *WebhookRepository) CreateSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	return db.Create(subscription).Error
+}
+
+func (w *WebhookRepository) UpdateSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	return db.Save(subscription).Error
+}
+
+func (w *WebhookRepository) DeleteSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", subscription.ID).Delete(&webhook.Delivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subscription).Error
+	})
+}
+
+func (w *WebhookRepository) GetSubscription(ctx context.Context, id string) (*webhook.Subscription, error) {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	var subscription webhook.Subscription
+	tx := db.First(&subscription, "id = ?", id)
+	return &subscription, tx.Error
+}
+
+func (w *WebhookRepository) GetSubscriptions(ctx context.Context, createdBy string) ([]webhook.Subscription, error) {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	var subscriptions []webhook.Subscription
+	tx := db.Order("created_on")
+	if createdBy != "" {
+		tx = tx.Where("created_by = ?", createdBy)
+	}
+	tx = tx.Find(&subscriptions)
+	return subscriptions, tx.Error
+}
+
+func (w *WebhookRepository) CreateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	return db.Create(delivery).Error
+}
+
+func (w *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	return db.Save(delivery).Error
+}
+
+func (w *WebhookRepository) GetDelivery(ctx context.Context, id string) (*webhook.Delivery, error) {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	var delivery webhook.Delivery
+	tx := db.First(&delivery, "id = ?", id)
+	return &delivery, tx.Error
+}
+
+func (w *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhook.Delivery, error) {
+	db, cancel := w.db(ctx)
+	defer cancel()
+	var deliveries []webhook.Delivery
+	tx := db.Where("subscription_id = ?", subscriptionID).Order("created_on DESC, id DESC").Limit(limit).Find(&deliveries)
+	return deliveries, tx.Error
+}
+
+func (w *WebhookRepository) db(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	return withTimeout(ctx, w.Db, w.Timeout)
+}
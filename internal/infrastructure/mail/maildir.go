@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MaildirMailer delivers every message into a local Maildir instead of
+// sending it, so campaigns can be inspected during development with any mail
+// client.
+type MaildirMailer struct {
+	Dir           string
+	From          string
+	BounceAddress string
+	sequence      atomic.Int64
+}
+
+func NewMaildirMailer(dir string, from string) (*MaildirMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &MaildirMailer{Dir: dir, From: from, BounceAddress: from}, nil
+}
+
+func (m *MaildirMailer) Send(ctx context.Context, message *campaign.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + "." +
+		strconv.FormatInt(m.sequence.Add(1), 10) + "." + hostname
+
+	// written to tmp/ and renamed so readers never see a partial message
+	tmp := filepath.Join(m.Dir, "tmp", name)
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	msg := newMessage(m.From, m.BounceAddress, message)
+	msg.SetHeader("Return-Path", "<"+VerpAddress(m.BounceAddress, message.ContactID)+">")
+	_, err = msg.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(m.Dir, "new", name))
+}
@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MaildirMailer_should_deliver_message_to_new(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	mailer, _ := NewMaildirMailer(dir, "campaigns@emailn.com")
+
+	err := mailer.Send(context.Background(), &campaign.Message{ContactID: "c1", To: "teste@test.com", Subject: "CampanhaX", Body: "Hi"})
+
+	assert.Nil(err)
+	files, _ := os.ReadDir(filepath.Join(dir, "new"))
+	assert.Len(files, 1)
+	content, _ := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	assert.Contains(string(content), "Return-Path: <campaigns+c1@emailn.com>")
+	assert.Contains(string(content), "Message-ID: <emailn.c1@emailn.com>")
+	assert.Contains(string(content), "To: teste@test.com")
+}
+
+func Test_Verp_round_trip(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("bounces+c1@emailn.com", VerpAddress("bounces@emailn.com", "c1"))
+	assert.Equal("c1", ContactIDFromVerp("bounces@emailn.com", "<bounces+c1@emailn.com>"))
+	assert.Equal("", ContactIDFromVerp("bounces@emailn.com", "bounces@emailn.com"))
+	assert.Equal("", ContactIDFromVerp("bounces@emailn.com", "someone+tag@emailn.com"))
+	assert.Equal("", ContactIDFromVerp("bounces@emailn.com", "bounces+c1@other.com"))
+	assert.Equal("c1", ContactIDFromMessageID(MessageID("c1", "emailn.com")))
+	assert.Equal("", ContactIDFromMessageID("<other@emailn.com>"))
+}
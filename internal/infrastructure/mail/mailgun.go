@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MailgunMailer speaks the Mailgun messages API: one request per batch with
+// the per-recipient values sent as recipient-variables.
+type MailgunMailer struct {
+	config ProviderConfig
+}
+
+type mailgunResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// placeholders of the campaign body, rewritten to %recipient.name%
+var placeholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+func NewMailgunMailer(config ProviderConfig) (*MailgunMailer, error) {
+	// Mailgun accepts up to 1000 recipients per request
+	if err := config.validate(1000); err != nil {
+		return nil, err
+	}
+	return &MailgunMailer{config: config}, nil
+}
+
+func (m *MailgunMailer) BatchSize() int {
+	return m.config.BatchSize
+}
+
+func (m *MailgunMailer) Send(ctx context.Context, message *campaign.Message) error {
+	return m.SendBatch(ctx, []*campaign.Message{message})
+}
+
+func (m *MailgunMailer) SendBatch(ctx context.Context, messages []*campaign.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	unique, sentAs := uniqueRecipients(messages)
+	variables := make(map[string]map[string]string, len(unique))
+	form := url.Values{}
+	form.Set("from", m.config.From)
+	form.Set("subject", messages[0].Subject)
+	form.Set("html", placeholder.ReplaceAllString(messages[0].Body, "%recipient.$1%"))
+	form.Set("v:campaign_id", messages[0].CampaignID)
+	form.Set("v:contact_id", "%recipient.contact_id%")
+	for _, message := range unique {
+		form.Add("to", message.To)
+		variables[message.To] = message.Substitutions
+	}
+	recipientVariables, err := json.Marshal(variables)
+	if err != nil {
+		return err
+	}
+	form.Set("recipient-variables", string(recipientVariables))
+
+	endpoint := strings.TrimRight(m.config.BaseURL, "/") + "/v3/" + m.config.Domain + "/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", m.config.APIKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := m.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return providerError("mailgun", res)
+	}
+
+	var response mailgunResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return err
+	}
+	setProviderMessageIDs(response.ID, messages, sentAs)
+	return nil
+}
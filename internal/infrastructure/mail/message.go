@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"emailn/internal/domain/campaign"
+
+	"gopkg.in/gomail.v2"
+)
+
+// newMessage builds the MIME message sent to a contact. The Message-ID
+// encodes the contact so bounces can be matched back to it, and is kept as
+// the provider message id.
+func newMessage(from string, bounceAddress string, message *campaign.Message) *gomail.Message {
+	message.ProviderMessageID = MessageID(message.ContactID, domainOf(bounceAddress))
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", message.To)
+	m.SetHeader("Subject", message.Subject)
+	m.SetHeader("Message-ID", message.ProviderMessageID)
+	m.SetBody("text/html", message.RenderedBody())
+	return m
+}
@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"emailn/internal/domain/campaign"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProviderConfig configures the transports that send through the REST API of
+// a transactional mail provider instead of SMTP. The provider sets the
+// envelope sender and handles the bounces itself, so no VERP address is sent
+// and the bounce processing, which matches the contacts by it, does not see
+// the bounces of these transports.
+type ProviderConfig struct {
+	// BaseURL of the API, e.g. https://api.sendgrid.com or
+	// https://api.mailgun.net; tests point it to an httptest server.
+	BaseURL string
+	APIKey  string
+	// Domain is the sending domain, required by Mailgun style APIs.
+	Domain    string
+	From      string
+	BatchSize int
+	Client    *http.Client
+}
+
+func (c *ProviderConfig) validate(defaultBatchSize int) error {
+	if c.BaseURL == "" || c.APIKey == "" {
+		return errors.New("mail provider url and api key are required")
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return nil
+}
+
+// uniqueRecipients returns the messages of the batch to send, one per
+// address: a provider would mail an address listed twice twice, or merge its
+// recipient-variables. sentAs holds, for every message, the one sent to its
+// address.
+func uniqueRecipients(messages []*campaign.Message) (unique []*campaign.Message, sentAs []*campaign.Message) {
+	first := make(map[string]*campaign.Message, len(messages))
+	sentAs = make([]*campaign.Message, len(messages))
+	for i, message := range messages {
+		address := strings.ToLower(message.To)
+		if _, ok := first[address]; !ok {
+			first[address] = message
+			unique = append(unique, message)
+		}
+		sentAs[i] = first[address]
+	}
+	return unique, sentAs
+}
+
+// setProviderMessageIDs gives every recipient of the batch its own id: the
+// id of the request, carried by the events of the provider, with the contact
+// id sent along as a custom variable. The duplicates of an address get the
+// id of the message sent to it.
+func setProviderMessageIDs(requestID string, messages []*campaign.Message, sentAs []*campaign.Message) {
+	for i, message := range messages {
+		message.ProviderMessageID = requestID + "/" + sentAs[i].ContactID
+	}
+}
+
+// providerError returns the error of a response that is not a success. Only
+// a timeout, a rate limit or a server error is worth retrying, any other
+// status is campaign.ErrPermanent.
+func providerError(provider string, res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err := fmt.Errorf("%s: unexpected status %d: %s", provider, res.StatusCode, body)
+	switch {
+	case res.StatusCode == http.StatusRequestTimeout, res.StatusCode == http.StatusTooManyRequests, res.StatusCode >= 500:
+		return err
+	}
+	return fmt.Errorf("%w: %w", campaign.ErrPermanent, err)
+}
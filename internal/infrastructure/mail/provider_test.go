@@ -0,0 +1,154 @@
+package mail
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func providerMessages() []*campaign.Message {
+	return []*campaign.Message{
+		{CampaignID: "cp1", ContactID: "c1", To: "um@test.com", Subject: "CampanhaX", Body: "Hi {{email}}",
+			Substitutions: map[string]string{"email": "um@test.com", "contact_id": "c1"}},
+		{CampaignID: "cp1", ContactID: "c2", To: "dois@test.com", Subject: "CampanhaX", Body: "Hi {{email}}",
+			Substitutions: map[string]string{"email": "dois@test.com", "contact_id": "c2"}},
+	}
+}
+
+func Test_SendGridMailer_should_send_batch_with_substitutions(t *testing.T) {
+	assert := assert.New(t)
+	var received sendGridRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/v3/mail/send", r.URL.Path)
+		assert.Equal("Bearer key", r.Header.Get("Authorization"))
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("X-Message-Id", "sg-123")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	mailer, _ := NewSendGridMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key", From: "campaigns@emailn.com"})
+	messages := providerMessages()
+
+	err := mailer.SendBatch(context.Background(), messages)
+
+	assert.Nil(err)
+	assert.Len(received.Personalizations, 2)
+	assert.Equal("dois@test.com", received.Personalizations[1].To[0].Email)
+	assert.Equal("dois@test.com", received.Personalizations[1].Substitutions["{{email}}"])
+	assert.Equal("c2", received.Personalizations[1].CustomArgs["contact_id"])
+	assert.Equal("Hi {{email}}", received.Content[0].Value)
+	assert.Equal("sg-123/c1", messages[0].ProviderMessageID)
+	assert.Equal("sg-123/c2", messages[1].ProviderMessageID)
+}
+
+func Test_SendGridMailer_should_send_once_to_an_address_listed_twice(t *testing.T) {
+	assert := assert.New(t)
+	var received sendGridRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("X-Message-Id", "sg-123")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	mailer, _ := NewSendGridMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key", From: "campaigns@emailn.com"})
+	messages := providerMessages()
+	messages[1].To = "UM@test.com"
+
+	err := mailer.SendBatch(context.Background(), messages)
+
+	assert.Nil(err)
+	assert.Len(received.Personalizations, 1)
+	assert.Equal("sg-123/c1", messages[1].ProviderMessageID)
+}
+
+func Test_SendGridMailer_should_return_error_when_provider_fails(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	mailer, _ := NewSendGridMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key"})
+
+	err := mailer.SendBatch(context.Background(), providerMessages())
+
+	assert.ErrorIs(err, campaign.ErrPermanent)
+}
+
+func Test_MailgunMailer_should_return_a_retryable_error_when_rate_limited(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		mailer, _ := NewMailgunMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key", Domain: "emailn.com"})
+
+		err := mailer.SendBatch(context.Background(), providerMessages())
+
+		assert.NotNil(t, err, status)
+		assert.NotErrorIs(t, err, campaign.ErrPermanent, status)
+		server.Close()
+	}
+}
+
+func Test_MailgunMailer_should_return_a_permanent_error_when_the_request_is_refused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	mailer, _ := NewMailgunMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key", Domain: "emailn.com"})
+
+	err := mailer.SendBatch(context.Background(), providerMessages())
+
+	assert.ErrorIs(t, err, campaign.ErrPermanent)
+}
+
+func Test_MailgunMailer_should_send_batch_with_recipient_variables(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/v3/emailn.com/messages", r.URL.Path)
+		user, password, _ := r.BasicAuth()
+		assert.Equal("api", user)
+		assert.Equal("key", password)
+		r.ParseForm()
+		assert.Equal([]string{"um@test.com", "dois@test.com"}, r.PostForm["to"])
+		assert.Equal("Hi %recipient.email%", r.PostForm.Get("html"))
+		var variables map[string]map[string]string
+		json.Unmarshal([]byte(r.PostForm.Get("recipient-variables")), &variables)
+		assert.Equal("c2", variables["dois@test.com"]["contact_id"])
+		w.Write([]byte(`{"id": "<mg-123@emailn.com>", "message": "Queued. Thank you."}`))
+	}))
+	defer server.Close()
+	mailer, _ := NewMailgunMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key", Domain: "emailn.com", From: "campaigns@emailn.com"})
+	messages := providerMessages()
+
+	err := mailer.SendBatch(context.Background(), messages)
+
+	assert.Nil(err)
+	assert.Equal("<mg-123@emailn.com>/c1", messages[0].ProviderMessageID)
+	assert.Equal("<mg-123@emailn.com>/c2", messages[1].ProviderMessageID)
+}
+
+func Test_MailgunMailer_should_keep_the_variables_of_an_address_listed_twice(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal([]string{"um@test.com"}, r.PostForm["to"])
+		var variables map[string]map[string]string
+		json.Unmarshal([]byte(r.PostForm.Get("recipient-variables")), &variables)
+		assert.Equal("c1", variables["um@test.com"]["contact_id"])
+		w.Write([]byte(`{"id": "<mg-123@emailn.com>", "message": "Queued. Thank you."}`))
+	}))
+	defer server.Close()
+	mailer, _ := NewMailgunMailer(ProviderConfig{BaseURL: server.URL, APIKey: "key", Domain: "emailn.com", From: "campaigns@emailn.com"})
+	messages := providerMessages()
+	messages[1].To = "um@test.com"
+
+	err := mailer.SendBatch(context.Background(), messages)
+
+	assert.Nil(err)
+	assert.Equal("<mg-123@emailn.com>/c1", messages[1].ProviderMessageID)
+}
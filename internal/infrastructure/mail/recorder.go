@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"sync"
+)
+
+// Recorder keeps every message in memory instead of sending it. It is meant
+// for tests and for running the API without a mail server.
+type Recorder struct {
+	mutex    sync.Mutex
+	messages []campaign.Message
+	// Err, when set, is returned by Send for every message.
+	Err error
+}
+
+func (r *Recorder) Send(ctx context.Context, message *campaign.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	r.messages = append(r.messages, *message)
+	return nil
+}
+
+func (r *Recorder) Messages() []campaign.Message {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]campaign.Message(nil), r.messages...)
+}
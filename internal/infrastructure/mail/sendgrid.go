@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"emailn/internal/domain/campaign"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// SendGridMailer speaks the SendGrid v3 mail/send API: one request per batch
+// with a personalization, and its substitutions, for every recipient.
+type SendGridMailer struct {
+	config ProviderConfig
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To            []sendGridAddress `json:"to"`
+	Substitutions map[string]string `json:"substitutions,omitempty"`
+	CustomArgs    map[string]string `json:"custom_args,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+func NewSendGridMailer(config ProviderConfig) (*SendGridMailer, error) {
+	// SendGrid accepts up to 1000 personalizations per request
+	if err := config.validate(1000); err != nil {
+		return nil, err
+	}
+	return &SendGridMailer{config: config}, nil
+}
+
+func (m *SendGridMailer) BatchSize() int {
+	return m.config.BatchSize
+}
+
+func (m *SendGridMailer) Send(ctx context.Context, message *campaign.Message) error {
+	return m.SendBatch(ctx, []*campaign.Message{message})
+}
+
+func (m *SendGridMailer) SendBatch(ctx context.Context, messages []*campaign.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	unique, sentAs := uniqueRecipients(messages)
+	request := sendGridRequest{
+		From:    sendGridAddress{Email: m.config.From},
+		Subject: messages[0].Subject,
+		Content: []sendGridContent{{Type: "text/html", Value: messages[0].Body}},
+	}
+	for _, message := range unique {
+		substitutions := make(map[string]string, len(message.Substitutions))
+		for name, value := range message.Substitutions {
+			substitutions["{{"+name+"}}"] = value
+		}
+		request.Personalizations = append(request.Personalizations, sendGridPersonalization{
+			To:            []sendGridAddress{{Email: message.To}},
+			Substitutions: substitutions,
+			CustomArgs:    map[string]string{"campaign_id": message.CampaignID, "contact_id": message.ContactID},
+		})
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(m.config.BaseURL, "/")+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := m.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return providerError("sendgrid", res)
+	}
+
+	id := res.Header.Get("X-Message-Id")
+	if id == "" {
+		return errors.New("sendgrid: response without X-Message-Id")
+	}
+	setProviderMessageIDs(id, messages, sentAs)
+	return nil
+}
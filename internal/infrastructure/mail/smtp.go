@@ -0,0 +1,295 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/infrastructure/metrics"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TLSModeStartTLS = "starttls"
+	TLSModeTLS      = "tls"
+)
+
+type SMTPConfig struct {
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	From          string
+	BounceAddress string
+	// TLSMode is empty (upgrade with STARTTLS when the server offers it),
+	// starttls (refuse a server not offering it) or tls (implicit TLS,
+	// usually port 465).
+	TLSMode    string
+	SkipVerify bool
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// SMTPMailer sends through an SMTP relay, reusing one connection for every
+// message until the relay drops it.
+type SMTPMailer struct {
+	config    SMTPConfig
+	tlsConfig *tls.Config
+	mutex     sync.Mutex
+	client    *smtp.Client
+}
+
+func NewSMTPMailer(config SMTPConfig) (*SMTPMailer, error) {
+	if config.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	if config.BounceAddress == "" {
+		config.BounceAddress = config.From
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	switch config.TLSMode {
+	case "", TLSModeStartTLS, TLSModeTLS:
+	default:
+		return nil, fmt.Errorf("invalid smtp tls mode %q", config.TLSMode)
+	}
+
+	tlsConfig := &tls.Config{ServerName: config.Host, InsecureSkipVerify: config.SkipVerify}
+	return &SMTPMailer{config: config, tlsConfig: tlsConfig}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message *campaign.Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	reused := m.client != nil
+	err := m.send(ctx, message)
+	if err != nil && reused && retryable(err) && ctx.Err() == nil {
+		// the relay may have closed an idle connection, try once more on a new one
+		m.config.Logger.Warn("smtp send failed, retrying on a new connection",
+			"campaign_id", message.CampaignID, "contact_id", message.ContactID, "error", err)
+		m.drop()
+		metrics.CountSMTPRetry()
+		err = m.send(ctx, message)
+	}
+	return err
+}
+
+func (m *SMTPMailer) send(ctx context.Context, message *campaign.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.client == nil {
+		client, err := m.dial(ctx)
+		if err != nil {
+			return err
+		}
+		m.config.Logger.Debug("smtp connected", "host", m.config.Host, "port", m.config.Port)
+		m.client = client
+	}
+
+	// net/smtp takes no context, closing the connection is what aborts a
+	// send stuck on the relay
+	client := m.client
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	envelopeFrom := VerpAddress(m.config.BounceAddress, message.ContactID)
+	start := time.Now()
+	err := transact(client, envelopeFrom, message.To, newMessage(m.config.From, m.config.BounceAddress, message))
+	if !stop() {
+		m.client = nil
+		err = fmt.Errorf("smtp send aborted: %w", context.Cause(ctx))
+	} else if err != nil && client.Reset() != nil {
+		// the transaction cannot be cleared, the next message needs a new
+		// connection
+		m.drop()
+	}
+	metrics.ObserveSMTPSend(start, err)
+	return err
+}
+
+// dataError wraps an error raised once the message was sent with DATA: the
+// relay may have taken it already, sending it again could deliver it twice.
+type dataError struct {
+	error
+}
+
+func (e dataError) Unwrap() error {
+	return e.error
+}
+
+// transact runs the mail transaction of one message. Only a permanent
+// refusal of the recipient is ErrRecipientRejected, one of the sender or of
+// the data fails the send.
+func transact(client *smtp.Client, from string, to string, message io.WriterTo) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		var smtpErr *textproto.Error
+		if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+			return fmt.Errorf("%w: %s", campaign.ErrRecipientRejected, smtpErr.Msg)
+		}
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := message.WriteTo(w); err != nil {
+		w.Close()
+		return dataError{err}
+	}
+	if err := w.Close(); err != nil {
+		return dataError{err}
+	}
+	return nil
+}
+
+// retryable tells whether a failed send may work on a new connection: an
+// error before the message went out with DATA, like a connection the relay
+// closed while idle, but neither a permanent refusal of the relay nor an
+// error once the relay may have the message.
+func retryable(err error) bool {
+	var smtpErr *textproto.Error
+	var afterData dataError
+	return !errors.Is(err, campaign.ErrRecipientRejected) && !errors.As(err, &afterData) &&
+		!(errors.As(err, &smtpErr) && smtpErr.Code >= 500)
+}
+
+// dial connects to the relay, upgrading to TLS with STARTTLS when it offers
+// it or TLSMode requires it, and authenticates when there is a username.
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port)))
+	if err != nil {
+		return nil, err
+	}
+	if m.config.TLSMode == TLSModeTLS {
+		conn = tls.Client(conn, m.tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if m.config.TLSMode != TLSModeTLS {
+		ok, _ := client.Extension("STARTTLS")
+		if !ok && m.config.TLSMode == TLSModeStartTLS {
+			client.Close()
+			return nil, errors.New("smtp relay does not offer STARTTLS, required by the starttls tls mode")
+		}
+		if ok {
+			if err := client.StartTLS(m.tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+	}
+	// no username means the relay does not require authentication
+	if ok, mechanisms := client.Extension("AUTH"); ok && m.config.Username != "" {
+		if err := client.Auth(m.auth(mechanisms)); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (m *SMTPMailer) auth(mechanisms string) smtp.Auth {
+	switch {
+	case strings.Contains(mechanisms, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(m.config.Username, m.config.Password)
+	case strings.Contains(mechanisms, "LOGIN") && !strings.Contains(mechanisms, "PLAIN"):
+		return &loginAuth{username: m.config.Username, password: m.config.Password}
+	default:
+		return smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+}
+
+// drop closes the connection without waiting for the relay.
+func (m *SMTPMailer) drop() {
+	if m.client != nil {
+		m.client.Close()
+		m.client = nil
+	}
+}
+
+func (m *SMTPMailer) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.client == nil {
+		return nil
+	}
+	err := m.client.Quit()
+	m.client = nil
+	return err
+}
+
+// Ping checks the relay accepts connections and greets with a 220 banner.
+func (m *SMTPMailer) Ping(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if m.config.TLSMode == TLSModeTLS {
+		tlsConn := tls.Client(conn, m.tlsConfig)
+		defer tlsConn.Close()
+		conn = tlsConn
+	}
+
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(banner, "220") {
+		return fmt.Errorf("unexpected smtp banner %q", strings.TrimSpace(banner))
+	}
+	return nil
+}
+
+// loginAuth is the LOGIN mechanism, for the relays offering it without
+// PLAIN.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("smtp: LOGIN authentication needs TLS")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch {
+	case bytes.EqualFold(fromServer, []byte("Username:")):
+		return []byte(a.username), nil
+	case bytes.EqualFold(fromServer, []byte("Password:")):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("smtp: unexpected LOGIN challenge %q", fromServer)
+	}
+}
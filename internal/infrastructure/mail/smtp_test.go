@@ -0,0 +1,213 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"emailn/internal/domain/campaign"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRelay is an SMTP server refusing the recipients of rejected and the
+// senders of a MAIL FROM containing refusedSender. Like a real relay, it
+// refuses a MAIL in a transaction not reset. It hangs up after a message
+// delivered when closeAfterMessage is set, and before answering the data
+// when hangUpAfterData is.
+type fakeRelay struct {
+	listener          net.Listener
+	rejected          string
+	refusedSender     string
+	closeAfterMessage bool
+	hangUpAfterData   bool
+
+	mutex     sync.Mutex
+	delivered []string
+	resets    int
+}
+
+func newFakeRelay(t *testing.T) *fakeRelay {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay := &fakeRelay{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go relay.serve()
+	return relay
+}
+
+func (f *fakeRelay) mailer(t *testing.T) *SMTPMailer {
+	address := f.listener.Addr().(*net.TCPAddr)
+	mailer, err := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: address.Port, From: "campaigns@emailn.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mailer.Close() })
+	return mailer
+}
+
+func (f *fakeRelay) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.session(conn)
+	}
+}
+
+func (f *fakeRelay) session(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	inTransaction := false
+	var to string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(command, "MAIL FROM"):
+			if inTransaction {
+				reply("503 5.5.1 nested MAIL command")
+			} else if f.refusedSender != "" && strings.Contains(command, strings.ToUpper(f.refusedSender)) {
+				reply("550 5.7.1 sender refused")
+			} else {
+				inTransaction = true
+				reply("250 ok")
+			}
+		case strings.HasPrefix(command, "RCPT TO"):
+			if f.rejected != "" && strings.Contains(command, strings.ToUpper(f.rejected)) {
+				reply("550 5.1.1 no such user")
+			} else {
+				to = strings.TrimSpace(line[len("RCPT TO:"):])
+				reply("250 ok")
+			}
+		case command == "DATA":
+			reply("354 go ahead")
+			for {
+				data, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if data == ".\r\n" {
+					break
+				}
+			}
+			f.mutex.Lock()
+			f.delivered = append(f.delivered, to)
+			hangUp, closeAfter := f.hangUpAfterData, f.closeAfterMessage
+			f.mutex.Unlock()
+			if hangUp {
+				return
+			}
+			inTransaction = false
+			reply("250 queued")
+			if closeAfter {
+				return
+			}
+		case command == "RSET":
+			f.mutex.Lock()
+			f.resets++
+			f.mutex.Unlock()
+			inTransaction = false
+			reply("250 ok")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func smtpMessage(n int, to string) *campaign.Message {
+	return &campaign.Message{CampaignID: "cp1", ContactID: "c" + strconv.Itoa(n), To: to, Subject: "CampanhaX", Body: "Hi"}
+}
+
+func Test_SMTPMailer_should_go_on_sending_after_a_rejected_recipient(t *testing.T) {
+	assert := assert.New(t)
+	relay := newFakeRelay(t)
+	relay.rejected = "bad@test.com"
+	mailer := relay.mailer(t)
+
+	first := mailer.Send(context.Background(), smtpMessage(1, "um@test.com"))
+	rejected := mailer.Send(context.Background(), smtpMessage(2, "bad@test.com"))
+	after := mailer.Send(context.Background(), smtpMessage(3, "tres@test.com"))
+
+	assert.Nil(first)
+	assert.ErrorIs(rejected, campaign.ErrRecipientRejected)
+	assert.Nil(after)
+	assert.Equal([]string{"<um@test.com>", "<tres@test.com>"}, relay.delivered)
+	assert.Equal(1, relay.resets)
+}
+
+func Test_SMTPMailer_should_fail_the_send_when_the_sender_is_refused(t *testing.T) {
+	assert := assert.New(t)
+	relay := newFakeRelay(t)
+	relay.refusedSender = "bounces"
+	mailer, _ := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: relay.listener.Addr().(*net.TCPAddr).Port,
+		From: "campaigns@emailn.com", BounceAddress: "bounces@emailn.com"})
+	defer mailer.Close()
+
+	err := mailer.Send(context.Background(), smtpMessage(1, "um@test.com"))
+
+	assert.NotNil(err)
+	assert.NotErrorIs(err, campaign.ErrRecipientRejected)
+	assert.Empty(relay.delivered)
+}
+
+func Test_SMTPMailer_should_retry_on_a_new_connection_when_the_relay_closed_the_idle_one(t *testing.T) {
+	assert := assert.New(t)
+	relay := newFakeRelay(t)
+	relay.closeAfterMessage = true
+	mailer := relay.mailer(t)
+
+	first := mailer.Send(context.Background(), smtpMessage(1, "um@test.com"))
+	second := mailer.Send(context.Background(), smtpMessage(2, "dois@test.com"))
+
+	assert.Nil(first)
+	assert.Nil(second)
+	assert.Equal([]string{"<um@test.com>", "<dois@test.com>"}, relay.delivered)
+}
+
+func Test_SMTPMailer_should_not_retry_once_the_data_was_sent(t *testing.T) {
+	assert := assert.New(t)
+	relay := newFakeRelay(t)
+	mailer := relay.mailer(t)
+	first := mailer.Send(context.Background(), smtpMessage(1, "um@test.com"))
+	relay.mutex.Lock()
+	relay.hangUpAfterData = true
+	relay.mutex.Unlock()
+
+	second := mailer.Send(context.Background(), smtpMessage(2, "dois@test.com"))
+
+	assert.Nil(first)
+	assert.NotNil(second)
+	relay.mutex.Lock()
+	defer relay.mutex.Unlock()
+	assert.Equal([]string{"<um@test.com>", "<dois@test.com>"}, relay.delivered)
+}
+
+func Test_SMTPMailer_should_refuse_a_relay_without_STARTTLS_in_starttls_mode(t *testing.T) {
+	assert := assert.New(t)
+	relay := newFakeRelay(t)
+	mailer, _ := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: relay.listener.Addr().(*net.TCPAddr).Port,
+		From: "campaigns@emailn.com", TLSMode: TLSModeStartTLS})
+	defer mailer.Close()
+
+	err := mailer.Send(context.Background(), smtpMessage(1, "um@test.com"))
+
+	assert.ErrorContains(err, "STARTTLS")
+	assert.Empty(relay.delivered)
+}
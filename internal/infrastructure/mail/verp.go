@@ -0,0 +1,53 @@
+package mail
+
+import "strings"
+
+const messageIDPrefix = "emailn."
+
+// VerpAddress encodes the contact id in the envelope sender (VERP), so the
+// bounce for bounces@example.com comes back to bounces+<contactID>@example.com.
+func VerpAddress(bounceAddress string, contactID string) string {
+	at := strings.LastIndex(bounceAddress, "@")
+	if at < 0 {
+		return bounceAddress
+	}
+	return bounceAddress[:at] + "+" + contactID + bounceAddress[at:]
+}
+
+// ContactIDFromVerp returns the contact id encoded by VerpAddress for the
+// bounceAddress or an empty string when the address is not one of its VERP
+// addresses, like another plus-addressed one.
+func ContactIDFromVerp(bounceAddress string, address string) string {
+	address = strings.Trim(strings.TrimSpace(address), "<>")
+	at := strings.LastIndex(address, "@")
+	bounceAt := strings.LastIndex(bounceAddress, "@")
+	if at < 0 || bounceAt < 0 || !strings.EqualFold(address[at:], bounceAddress[bounceAt:]) {
+		return ""
+	}
+	prefix := bounceAddress[:bounceAt] + "+"
+	local := address[:at]
+	if len(local) <= len(prefix) || !strings.EqualFold(local[:len(prefix)], prefix) {
+		return ""
+	}
+	return local[len(prefix):]
+}
+
+// MessageID builds the Message-ID header of the message sent to a contact.
+func MessageID(contactID string, domain string) string {
+	return "<" + messageIDPrefix + contactID + "@" + domain + ">"
+}
+
+// ContactIDFromMessageID returns the contact id encoded by MessageID or an
+// empty string when the id was not generated by us.
+func ContactIDFromMessageID(messageID string) string {
+	messageID = strings.Trim(strings.TrimSpace(messageID), "<>")
+	at := strings.LastIndex(messageID, "@")
+	if at < 0 || !strings.HasPrefix(messageID, messageIDPrefix) {
+		return ""
+	}
+	return messageID[len(messageIDPrefix):at]
+}
+
+func domainOf(address string) string {
+	return address[strings.LastIndex(address, "@")+1:]
+}
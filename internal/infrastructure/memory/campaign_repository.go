@@ -0,0 +1,315 @@
+// Package memory keeps the repositories in memory, to run the API without a
+// database. Everything is lost when the process stops.
+package memory
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CampaignRepository behaves like database.CampaignRepository: GetBy loads
+// the contacts, Get and GetByStatus do not, Update saves the contacts of the
+// campaign changed since they were loaded, and the errors are the gorm ones.
+type CampaignRepository struct {
+	mutex sync.RWMutex
+	// campaigns are kept without their contacts, contactIDs has them in
+	// insertion order.
+	campaigns    map[string]campaign.Campaign
+	contacts     map[string]campaign.Contact
+	contactIDs   map[string][]string
+	suppressions map[string]campaign.Suppression
+}
+
+func NewCampaignRepository() *CampaignRepository {
+	return &CampaignRepository{
+		campaigns:    map[string]campaign.Campaign{},
+		contacts:     map[string]campaign.Contact{},
+		contactIDs:   map[string][]string{},
+		suppressions: map[string]campaign.Suppression{},
+	}
+}
+
+func (c *CampaignRepository) Create(ctx context.Context, newCampaign *campaign.Campaign) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.campaigns[newCampaign.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	for _, contact := range newCampaign.Contacts {
+		if _, ok := c.contacts[contact.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	c.save(newCampaign)
+	return nil
+}
+
+func (c *CampaignRepository) Update(ctx context.Context, updated *campaign.Campaign) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	saved, ok := c.campaigns[updated.ID]
+	if !ok || saved.Version != updated.Version {
+		return campaign.ErrVersionConflict
+	}
+	updated.Version++
+	for _, contact := range updated.ChangedContacts() {
+		contact.CampaignId = updated.ID
+		contact.MarkSaved()
+		c.saveContact(*contact)
+	}
+	stored := *updated
+	stored.Contacts = nil
+	c.campaigns[updated.ID] = stored
+	return nil
+}
+
+func (c *CampaignRepository) Get(ctx context.Context) ([]campaign.Campaign, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	campaigns := make([]campaign.Campaign, 0, len(c.campaigns))
+	for _, saved := range c.campaigns {
+		if saved.DeletedOn == nil {
+			campaigns = append(campaigns, saved)
+		}
+	}
+	return campaigns, nil
+}
+
+func (c *CampaignRepository) GetBy(ctx context.Context, id string) (*campaign.Campaign, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	saved, ok := c.campaigns[id]
+	if !ok || saved.DeletedOn != nil {
+		return &campaign.Campaign{}, gorm.ErrRecordNotFound
+	}
+	saved.Contacts = c.contactsOf(id)
+	return &saved, nil
+}
+
+func (c *CampaignRepository) GetDeletedBy(ctx context.Context, id string) (*campaign.Campaign, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	saved, ok := c.campaigns[id]
+	if !ok || saved.DeletedOn == nil {
+		return &campaign.Campaign{}, gorm.ErrRecordNotFound
+	}
+	saved.Contacts = c.contactsOf(id)
+	return &saved, nil
+}
+
+func (c *CampaignRepository) GetByStatus(ctx context.Context, status string) ([]campaign.Campaign, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var campaigns []campaign.Campaign
+	for _, saved := range c.campaigns {
+		if saved.Status == status && saved.DeletedOn == nil {
+			campaigns = append(campaigns, saved)
+		}
+	}
+	return campaigns, nil
+}
+
+func (c *CampaignRepository) GetStats(ctx context.Context, id string) (*campaign.Stats, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	saved, ok := c.campaigns[id]
+	if !ok || saved.DeletedOn != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	stats := campaign.Stats{
+		CampaignID: saved.ID,
+		Status:     saved.Status,
+		StartedOn:  saved.StartedOn,
+		FinishedOn: saved.FinishedOn,
+	}
+	for _, contact := range c.contactsOf(id) {
+		stats.Total++
+		if contact.SentOn != nil {
+			stats.Sent++
+		}
+		switch contact.Status {
+		case campaign.ContactFailed:
+			stats.Failed++
+		case campaign.ContactBounced:
+			stats.Bounced++
+		case campaign.ContactSuppressed:
+			stats.Suppressed++
+		}
+		if contact.OpenedOn != nil {
+			stats.Opened++
+		}
+		if contact.ClickedOn != nil {
+			stats.Clicked++
+		}
+		if contact.UnsubscribedOn != nil {
+			stats.Unsubscribed++
+		}
+	}
+	return &stats, nil
+}
+
+func (c *CampaignRepository) Delete(ctx context.Context, deleted *campaign.Campaign) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	saved, ok := c.campaigns[deleted.ID]
+	if !ok || saved.Version != deleted.Version {
+		return campaign.ErrVersionConflict
+	}
+	deleted.Version++
+	saved.Status = deleted.Status
+	saved.DeletedOn = deleted.DeletedOn
+	saved.Version = deleted.Version
+	c.campaigns[deleted.ID] = saved
+	return nil
+}
+
+func (c *CampaignRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var purged int64
+	for id, saved := range c.campaigns {
+		if saved.DeletedOn == nil || !saved.DeletedOn.Before(deletedBefore) {
+			continue
+		}
+		for _, contactID := range c.contactIDs[id] {
+			delete(c.contacts, contactID)
+		}
+		delete(c.contactIDs, id)
+		delete(c.campaigns, id)
+		purged++
+	}
+	return purged, nil
+}
+
+func (c *CampaignRepository) GetOwner(ctx context.Context, id string) (string, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	saved, ok := c.campaigns[id]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+	return saved.CreatedBy, nil
+}
+
+func (c *CampaignRepository) GetContactBy(ctx context.Context, id string) (*campaign.Contact, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	contact, ok := c.contacts[id]
+	if !ok {
+		return &campaign.Contact{}, gorm.ErrRecordNotFound
+	}
+	return &contact, nil
+}
+
+func (c *CampaignRepository) UpdateContact(ctx context.Context, contact *campaign.Contact) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	contact.MarkSaved()
+	c.saveContact(*contact)
+	return nil
+}
+
+func (c *CampaignRepository) UpdateContacts(ctx context.Context, contacts []*campaign.Contact) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, contact := range contacts {
+		contact.MarkSaved()
+		c.saveContact(*contact)
+	}
+	return nil
+}
+
+func (c *CampaignRepository) CreateSuppression(ctx context.Context, suppression *campaign.Suppression) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.suppressions[suppression.Email]; !ok {
+		c.suppressions[suppression.Email] = *suppression
+	}
+	return nil
+}
+
+func (c *CampaignRepository) GetSuppressedEmails(ctx context.Context, campaignID string) ([]string, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var emails []string
+	for _, contact := range c.contactsOf(campaignID) {
+		if _, ok := c.suppressions[strings.ToLower(contact.Email)]; ok {
+			emails = append(emails, contact.Email)
+		}
+	}
+	return emails, nil
+}
+
+func (c *CampaignRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	counts := map[string]int64{}
+	for _, saved := range c.campaigns {
+		counts[saved.Status]++
+	}
+	return counts, nil
+}
+
+func (c *CampaignRepository) CountPendingContacts(ctx context.Context) (int64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var count int64
+	for _, contact := range c.contacts {
+		owner, ok := c.campaigns[contact.CampaignId]
+		if ok && owner.Status == campaign.Started &&
+			(contact.Status == campaign.ContactPending || contact.Status == campaign.ContactSending) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// save stores a new campaign and its contacts.
+func (c *CampaignRepository) save(saved *campaign.Campaign) {
+	for i := range saved.Contacts {
+		saved.Contacts[i].CampaignId = saved.ID
+		c.saveContact(saved.Contacts[i])
+	}
+	stored := *saved
+	stored.Contacts = nil
+	c.campaigns[saved.ID] = stored
+}
+
+func (c *CampaignRepository) saveContact(contact campaign.Contact) {
+	if _, ok := c.contacts[contact.ID]; !ok {
+		c.contactIDs[contact.CampaignId] = append(c.contactIDs[contact.CampaignId], contact.ID)
+	}
+	c.contacts[contact.ID] = contact
+}
+
+func (c *CampaignRepository) contactsOf(campaignID string) []campaign.Contact {
+	ids := c.contactIDs[campaignID]
+	contacts := make([]campaign.Contact, 0, len(ids))
+	for _, id := range ids {
+		contacts = append(contacts, c.contacts[id])
+	}
+	return contacts
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCampaign() *campaign.Campaign {
+	created, _ := campaign.NewCampaign("Test Y", "body HI!", []string{"teste1@test.com", "Teste2@test.com"}, "teste@test.com.br")
+	return created
+}
+
+func Test_GetBy_should_not_share_state_with_caller(t *testing.T) {
+	assert := assert.New(t)
+	repository := NewCampaignRepository()
+	created := newCampaign()
+	repository.Create(context.Background(), created)
+
+	created.Name = "changed"
+	saved, _ := repository.GetBy(context.Background(), created.ID)
+	saved.Contacts[0].Status = campaign.ContactSent
+	again, _ := repository.GetBy(context.Background(), created.ID)
+
+	assert.Equal("Test Y", again.Name)
+	assert.Equal(campaign.ContactPending, again.Contacts[0].Status)
+}
+
+func Test_UpdateContact_should_be_safe_for_concurrent_use(t *testing.T) {
+	repository := NewCampaignRepository()
+	created := newCampaign()
+	repository.Create(context.Background(), created)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			contact := created.Contacts[0]
+			repository.UpdateContact(context.Background(), &contact)
+		}()
+		go func() {
+			defer wg.Done()
+			repository.GetBy(context.Background(), created.ID)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"emailn/internal/domain/idempotency"
+	"emailn/internal/domain/job"
+	"emailn/internal/domain/outbox"
+	"emailn/internal/domain/webhook"
+	"emailn/internal/infrastructure/repositorytest"
+	"testing"
+)
+
+func Test_CampaignRepository_contract(t *testing.T) {
+	repositorytest.RunCampaignRepository(t, func(t *testing.T) repositorytest.CampaignRepository {
+		return NewCampaignRepository()
+	})
+}
+
+func Test_IdempotencyRepository_contract(t *testing.T) {
+	repositorytest.RunIdempotencyRepository(t, func(t *testing.T) idempotency.Repository {
+		return NewIdempotencyRepository()
+	})
+}
+
+func Test_JobRepository_contract(t *testing.T) {
+	repositorytest.RunJobRepository(t, func(t *testing.T) job.Repository {
+		return NewJobRepository()
+	})
+}
+
+func Test_OutboxRepository_contract(t *testing.T) {
+	repositorytest.RunOutboxRepository(t, func(t *testing.T) outbox.Repository {
+		return NewOutboxRepository()
+	})
+}
+
+func Test_WebhookRepository_contract(t *testing.T) {
+	repositorytest.RunWebhookRepository(t, func(t *testing.T) webhook.Repository {
+		return NewWebhookRepository()
+	})
+}
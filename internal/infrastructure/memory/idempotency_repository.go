@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"emailn/internal/domain/idempotency"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type recordKey struct {
+	key       string
+	createdBy string
+}
+
+type IdempotencyRepository struct {
+	mutex   sync.Mutex
+	records map[recordKey]idempotency.Record
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: map[recordKey]idempotency.Record{}}
+}
+
+func (i *IdempotencyRepository) GetBy(ctx context.Context, key string, createdBy string) (*idempotency.Record, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	record, ok := i.records[recordKey{key, createdBy}]
+	if !ok {
+		return &idempotency.Record{}, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (i *IdempotencyRepository) Create(ctx context.Context, record *idempotency.Record) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if _, ok := i.records[recordKey{record.Key, record.CreatedBy}]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	i.records[recordKey{record.Key, record.CreatedBy}] = *record
+	return nil
+}
+
+func (i *IdempotencyRepository) Update(ctx context.Context, record *idempotency.Record) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.records[recordKey{record.Key, record.CreatedBy}] = *record
+	return nil
+}
+
+func (i *IdempotencyRepository) Delete(ctx context.Context, record *idempotency.Record) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	delete(i.records, recordKey{record.Key, record.CreatedBy})
+	return nil
+}
+
+func (i *IdempotencyRepository) Purge(ctx context.Context, createdBefore time.Time) (int64, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	var purged int64
+	for key, record := range i.records {
+		if record.CreatedOn.Before(createdBefore) {
+			delete(i.records, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
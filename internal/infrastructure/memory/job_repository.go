@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"emailn/internal/domain/job"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobRepository behaves like database.JobRepository, for a single process.
+type JobRepository struct {
+	mutex sync.Mutex
+	jobs  map[string]job.Job
+}
+
+func NewJobRepository() *JobRepository {
+	return &JobRepository{jobs: map[string]job.Job{}}
+}
+
+func (j *JobRepository) Create(ctx context.Context, created *job.Job) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if _, ok := j.jobs[created.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	for _, saved := range j.jobs {
+		if created.Key != "" && saved.Key == created.Key && active(saved) {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	j.jobs[created.ID] = *created
+	return nil
+}
+
+func (j *JobRepository) GetBy(ctx context.Context, id string) (*job.Job, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	saved, ok := j.jobs[id]
+	if !ok {
+		return &job.Job{}, gorm.ErrRecordNotFound
+	}
+	return &saved, nil
+}
+
+func (j *JobRepository) Claim(ctx context.Context, kinds []string, worker string, now time.Time, until time.Time) (*job.Job, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	var due *job.Job
+	for _, saved := range j.jobs {
+		if !slices.Contains(kinds, saved.Kind) || !active(saved) || saved.RunAt.After(now) ||
+			(saved.LockedUntil != nil && !saved.LockedUntil.Before(now)) {
+			continue
+		}
+		if due == nil || saved.RunAt.Before(due.RunAt) {
+			oldest := saved
+			due = &oldest
+		}
+	}
+	if due == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	due.Status = job.Running
+	due.Attempts++
+	due.LockedBy = worker
+	due.LockedUntil = &until
+	j.jobs[due.ID] = *due
+	return due, nil
+}
+
+func (j *JobRepository) Extend(ctx context.Context, leased *job.Job, until time.Time) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	saved, ok := j.jobs[leased.ID]
+	if !ok || saved.LockedBy != leased.LockedBy {
+		return job.ErrLeaseLost
+	}
+	saved.LockedUntil = &until
+	j.jobs[leased.ID] = saved
+	leased.LockedUntil = &until
+	return nil
+}
+
+func (j *JobRepository) Finish(ctx context.Context, finished *job.Job) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	saved, ok := j.jobs[finished.ID]
+	if !ok || saved.LockedBy != finished.LockedBy {
+		return job.ErrLeaseLost
+	}
+	finished.LockedBy = ""
+	finished.LockedUntil = nil
+	if finished.Status == job.Done {
+		delete(j.jobs, finished.ID)
+	} else {
+		j.jobs[finished.ID] = *finished
+	}
+	return nil
+}
+
+func active(saved job.Job) bool {
+	return saved.Status == job.Queued || saved.Status == job.Running
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"emailn/internal/domain/outbox"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs the functions as they are, the in-memory repositories have
+// no transactions: the writes done before an error are kept.
+type UnitOfWork struct{}
+
+func (UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// OutboxRepository behaves like database.OutboxRepository, for a single
+// relay.
+type OutboxRepository struct {
+	mutex    sync.Mutex
+	messages []outbox.Message
+}
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{}
+}
+
+func (o *OutboxRepository) Create(ctx context.Context, message *outbox.Message) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, saved := range o.messages {
+		if saved.ID == message.ID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	o.messages = append(o.messages, *message)
+	return nil
+}
+
+func (o *OutboxRepository) Next(ctx context.Context, now time.Time) (*outbox.Message, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var next *outbox.Message
+	for i, saved := range o.messages {
+		if saved.Status != outbox.Pending || saved.NextAttemptAt.After(now) {
+			continue
+		}
+		if next == nil || saved.CreatedOn.Before(next.CreatedOn) {
+			next = &o.messages[i]
+		}
+	}
+	if next == nil {
+		return &outbox.Message{}, gorm.ErrRecordNotFound
+	}
+	found := *next
+	return &found, nil
+}
+
+func (o *OutboxRepository) Update(ctx context.Context, message *outbox.Message) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i, saved := range o.messages {
+		if saved.ID == message.ID {
+			o.messages[i].Status = message.Status
+			o.messages[i].Attempts = message.Attempts
+			o.messages[i].NextAttemptAt = message.NextAttemptAt
+			o.messages[i].LastError = message.LastError
+			return nil
+		}
+	}
+	return nil
+}
+
+func (o *OutboxRepository) Delete(ctx context.Context, message *outbox.Message) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i, saved := range o.messages {
+		if saved.ID == message.ID {
+			o.messages = append(o.messages[:i], o.messages[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"emailn/internal/domain/webhook"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	mutex         sync.RWMutex
+	subscriptions map[string]webhook.Subscription
+	deliveries    map[string]webhook.Delivery
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		subscriptions: map[string]webhook.Subscription{},
+		deliveries:    map[string]webhook.Delivery{},
+	}
+}
+
+func (w *WebhookRepository) CreateSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.subscriptions[subscription.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	w.subscriptions[subscription.ID] = *subscription
+	return nil
+}
+
+func (w *WebhookRepository) UpdateSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.subscriptions[subscription.ID] = *subscription
+	return nil
+}
+
+func (w *WebhookRepository) DeleteSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for id, delivery := range w.deliveries {
+		if delivery.SubscriptionID == subscription.ID {
+			delete(w.deliveries, id)
+		}
+	}
+	delete(w.subscriptions, subscription.ID)
+	return nil
+}
+
+func (w *WebhookRepository) GetSubscription(ctx context.Context, id string) (*webhook.Subscription, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	subscription, ok := w.subscriptions[id]
+	if !ok {
+		return &webhook.Subscription{}, gorm.ErrRecordNotFound
+	}
+	return &subscription, nil
+}
+
+func (w *WebhookRepository) GetSubscriptions(ctx context.Context, createdBy string) ([]webhook.Subscription, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var subscriptions []webhook.Subscription
+	for _, subscription := range w.subscriptions {
+		if createdBy == "" || subscription.CreatedBy == createdBy {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	slices.SortFunc(subscriptions, func(a, b webhook.Subscription) int {
+		return a.CreatedOn.Compare(b.CreatedOn)
+	})
+	return subscriptions, nil
+}
+
+func (w *WebhookRepository) CreateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.deliveries[delivery.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	w.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (w *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (w *WebhookRepository) GetDelivery(ctx context.Context, id string) (*webhook.Delivery, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	delivery, ok := w.deliveries[id]
+	if !ok {
+		return &webhook.Delivery{}, gorm.ErrRecordNotFound
+	}
+	return &delivery, nil
+}
+
+func (w *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhook.Delivery, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var deliveries []webhook.Delivery
+	for _, delivery := range w.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b webhook.Delivery) int {
+		if byTime := b.CreatedOn.Compare(a.CreatedOn); byTime != 0 {
+			return byTime
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return deliveries[:min(limit, len(deliveries))], nil
+}
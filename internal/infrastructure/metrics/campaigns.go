@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CampaignCounter is the part of the campaign repository the collector reads
+// on every scrape.
+type CampaignCounter interface {
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+	CountPendingContacts(ctx context.Context) (int64, error)
+}
+
+var (
+	campaignsDesc = prometheus.NewDesc("emailn_campaigns", "Campaigns by status.", []string{"status"}, nil)
+	pendingDesc   = prometheus.NewDesc("emailn_pending_sends", "Contacts of started campaigns still waiting to be sent.", nil, nil)
+)
+
+// CampaignCollector reports the campaigns by status and the queue of pending
+// sends straight from the repository, so every replica agrees.
+type CampaignCollector struct {
+	Counter CampaignCounter
+}
+
+func (c *CampaignCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- campaignsDesc
+	ch <- pendingDesc
+}
+
+func (c *CampaignCollector) Collect(ch chan<- prometheus.Metric) {
+	// the scrape gives no context, the query deadline of the repository bounds it
+	ctx := context.Background()
+	statuses, err := c.Counter.CountByStatus(ctx)
+	if err != nil {
+		slog.Error("counting campaigns for metrics", "error", err)
+	}
+	for status, count := range statuses {
+		ch <- prometheus.MustNewConstMetric(campaignsDesc, prometheus.GaugeValue, float64(count), status)
+	}
+
+	pending, err := c.Counter.CountPendingContacts(ctx)
+	if err != nil {
+		slog.Error("counting pending sends for metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(pending))
+}
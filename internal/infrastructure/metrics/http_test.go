@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Middleware_should_count_requests_by_route_pattern(t *testing.T) {
+	assert := assert.New(t)
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/campaigns/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"a", "b"} {
+		req, _ := http.NewRequest("GET", "/campaigns/"+id, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(2.0, testutil.ToFloat64(httpRequests.WithLabelValues("/campaigns/{id}", "GET", "404")))
+}
+
+func Test_Messages_should_count_by_transport_and_result(t *testing.T) {
+	assert := assert.New(t)
+	metrics := Messages{Transport: "maildir"}
+
+	metrics.CountMessages("sent", 3)
+
+	assert.Equal(3.0, testutil.ToFloat64(messages.WithLabelValues("maildir", "sent")))
+}
+
+func Test_CountSMTPRetry_should_not_count_a_message(t *testing.T) {
+	assert := assert.New(t)
+	retried := testutil.ToFloat64(messages.WithLabelValues("smtp", "retried"))
+	reconnects := testutil.ToFloat64(smtpReconnectRetries)
+
+	CountSMTPRetry()
+
+	assert.Equal(retried, testutil.ToFloat64(messages.WithLabelValues("smtp", "retried")))
+	assert.Equal(reconnects+1, testutil.ToFloat64(smtpReconnectRetries))
+}
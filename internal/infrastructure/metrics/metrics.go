@@ -0,0 +1,53 @@
+// Package metrics exposes the Prometheus metrics of the API, the sending of
+// campaigns and the database.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "emailn_http_requests_total",
+		Help: "HTTP requests by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "emailn_http_request_duration_seconds",
+		Help:    "HTTP request latency by route pattern, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	messages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "emailn_messages_total",
+		Help: "Campaign messages by transport and result (sent, failed, retried).",
+	}, []string{"transport", "result"})
+
+	smtpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "emailn_smtp_send_duration_seconds",
+		Help:    "Time the SMTP relay took to accept or refuse a message.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"result"})
+
+	smtpReconnectRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "emailn_smtp_reconnect_retries_total",
+		Help: "SMTP sends tried again on a new connection after the relay dropped the previous one.",
+	})
+
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "emailn_db_query_duration_seconds",
+		Help:    "gorm query duration by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+)
+
+// Messages counts the outcome of campaign messages for one transport, it is
+// the campaign.Metrics of the service.
+type Messages struct {
+	Transport string
+}
+
+func (m Messages) CountMessages(result string, count int) {
+	messages.WithLabelValues(m.Transport, result).Add(float64(count))
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"emailn/internal/domain/campaign"
+	"errors"
+	"time"
+)
+
+// ObserveSMTPSend records how long the relay took for one message.
+func ObserveSMTPSend(start time.Time, err error) {
+	result := "ok"
+	if errors.Is(err, campaign.ErrRecipientRejected) {
+		result = "rejected"
+	} else if err != nil {
+		result = "error"
+	}
+	smtpDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// CountSMTPRetry counts a message sent again on a new connection after the
+// relay dropped the previous one. Its outcome is counted once, by the
+// campaign, in emailn_messages_total.
+func CountSMTPRetry() {
+	smtpReconnectRetries.Inc()
+}
@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleSweep is how often the buckets of the recipient domains and of the
+// campaigns are looked through for idle ones to drop.
+const idleSweep = time.Minute
+
+// Config holds the sending rates in messages per second. A zero rate means
+// unlimited.
+type Config struct {
+	PerSecond             float64
+	SenderDomainPerSecond float64
+	// RecipientDomains sets the rate of specific recipient domains such as
+	// gmail.com; every other domain uses RecipientDomainPerSecond.
+	RecipientDomains         map[string]float64
+	RecipientDomainPerSecond float64
+	// Burst is the number of messages that may go out at once before the
+	// rates apply. Defaults to 1.
+	Burst int
+}
+
+// Limiter spreads the sending with token buckets: one global, one per sender
+// domain, one per recipient domain and one per campaign that overrides its
+// rate with MaxPerSecond. A message waits until every bucket it belongs to
+// has a token. The buckets live in the process: N processes sending together
+// send up to N times the rates. The buckets of the recipient domains and of the campaigns are
+// dropped once idle, so they do not pile up over the life of the process.
+type Limiter struct {
+	config     Config
+	sender     string
+	global     *rate.Limiter
+	mutex      sync.Mutex
+	senders    map[string]*rate.Limiter
+	recipients map[string]*bucket
+	campaigns  map[string]*bucket
+	swept      time.Time
+}
+
+// bucket is a token bucket with when it was last handed out. Idle for longer
+// than its refill it is full again, the same as a new one.
+type bucket struct {
+	*rate.Limiter
+	used time.Time
+}
+
+func (b *bucket) idle(now time.Time) bool {
+	var refill time.Duration
+	if limit := b.Limit(); limit != rate.Inf && limit > 0 {
+		refill = time.Duration(float64(b.Burst()) / float64(limit) * float64(time.Second))
+	}
+	return now.Sub(b.used) > refill && b.TokensAt(now) >= float64(b.Burst())
+}
+
+func NewLimiter(config Config, from string) *Limiter {
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	return &Limiter{
+		config:     config,
+		sender:     domainOf(from),
+		global:     newBucket(config.PerSecond, config.Burst),
+		senders:    map[string]*rate.Limiter{},
+		recipients: map[string]*bucket{},
+		campaigns:  map[string]*bucket{},
+		swept:      time.Now(),
+	}
+}
+
+// Wait takes a token from every bucket of the message at once and waits for
+// the last of them to be due. When ctx is done first the tokens are given
+// back, a message that is not sent holds no place in any bucket.
+func (l *Limiter) Wait(ctx context.Context, message *campaign.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now()
+	buckets := l.buckets(message)
+	reservations := make([]*rate.Reservation, 0, len(buckets))
+	var delay time.Duration
+	for _, bucket := range buckets {
+		reservation := bucket.ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+		if !reservation.OK() {
+			cancelReservations(reservations, now)
+			return fmt.Errorf("rate: message exceeds the burst of %d", bucket.Burst())
+		}
+		delay = max(delay, reservation.DelayFrom(now))
+	}
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		cancelReservations(reservations, now)
+		return errors.New("rate: wait would exceed the context deadline")
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		cancelReservations(reservations, now)
+		return ctx.Err()
+	}
+}
+
+// cancelReservations gives back the tokens reserved at now. Cancelled as of
+// the time they were made, the reservations already due are given back too.
+func cancelReservations(reservations []*rate.Reservation, now time.Time) {
+	for _, reservation := range reservations {
+		reservation.CancelAt(now)
+	}
+}
+
+func (l *Limiter) buckets(message *campaign.Message) []*rate.Limiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.swept) >= idleSweep {
+		l.dropIdle(now)
+	}
+	buckets := []*rate.Limiter{l.global}
+	if _, ok := l.senders[l.sender]; !ok {
+		l.senders[l.sender] = newBucket(l.config.SenderDomainPerSecond, l.config.Burst)
+	}
+	buckets = append(buckets, l.senders[l.sender])
+
+	recipient := domainOf(message.To)
+	recipientBucket, ok := l.recipients[recipient]
+	if !ok {
+		perSecond, ok := l.config.RecipientDomains[recipient]
+		if !ok {
+			perSecond = l.config.RecipientDomainPerSecond
+		}
+		recipientBucket = &bucket{Limiter: newBucket(perSecond, l.config.Burst)}
+		l.recipients[recipient] = recipientBucket
+	}
+	recipientBucket.used = now
+	buckets = append(buckets, recipientBucket.Limiter)
+
+	if message.MaxPerSecond > 0 {
+		campaignBucket, ok := l.campaigns[message.CampaignID]
+		if !ok || float64(campaignBucket.Limit()) != message.MaxPerSecond {
+			campaignBucket = &bucket{Limiter: newBucket(message.MaxPerSecond, l.config.Burst)}
+			l.campaigns[message.CampaignID] = campaignBucket
+		}
+		campaignBucket.used = now
+		buckets = append(buckets, campaignBucket.Limiter)
+	}
+	return buckets
+}
+
+// dropIdle forgets the idle buckets of the recipient domains and of the
+// campaigns, the next message makes a new one.
+func (l *Limiter) dropIdle(now time.Time) {
+	for recipient, recipientBucket := range l.recipients {
+		if recipientBucket.idle(now) {
+			delete(l.recipients, recipient)
+		}
+	}
+	for campaignID, campaignBucket := range l.campaigns {
+		if campaignBucket.idle(now) {
+			delete(l.campaigns, campaignID)
+		}
+	}
+	l.swept = now
+}
+
+func newBucket(perSecond float64, burst int) *rate.Limiter {
+	if perSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, burst)
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+func domainOf(address string) string {
+	return strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+}
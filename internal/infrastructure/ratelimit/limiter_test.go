@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitFor(limiter *Limiter, messages ...*campaign.Message) time.Duration {
+	start := time.Now()
+	for _, message := range messages {
+		limiter.Wait(context.Background(), message)
+	}
+	return time.Since(start)
+}
+
+func Test_Limiter_should_not_wait_when_unlimited(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewLimiter(Config{}, "campaigns@emailn.com")
+	message := &campaign.Message{To: "um@gmail.com"}
+
+	elapsed := waitFor(limiter, message, message, message, message)
+
+	assert.Less(elapsed, 20*time.Millisecond)
+}
+
+func Test_Limiter_should_throttle_recipient_domain(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewLimiter(Config{RecipientDomains: map[string]float64{"gmail.com": 20}}, "campaigns@emailn.com")
+	gmail := &campaign.Message{To: "um@Gmail.com"}
+	other := &campaign.Message{To: "um@test.com"}
+
+	assert.Less(waitFor(limiter, other, other, other), 20*time.Millisecond)
+	assert.GreaterOrEqual(waitFor(limiter, gmail, gmail, gmail), 90*time.Millisecond)
+}
+
+func Test_Limiter_should_apply_campaign_override(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewLimiter(Config{PerSecond: 1000}, "campaigns@emailn.com")
+	message := &campaign.Message{CampaignID: "cp1", To: "um@test.com", MaxPerSecond: 20}
+
+	assert.GreaterOrEqual(waitFor(limiter, message, message, message), 90*time.Millisecond)
+}
+
+func Test_Limiter_should_drop_the_idle_buckets(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewLimiter(Config{RecipientDomainPerSecond: 10}, "campaigns@emailn.com")
+	idle := &campaign.Message{CampaignID: "cp1", To: "um@idle.com", MaxPerSecond: 10}
+	busy := &campaign.Message{CampaignID: "cp2", To: "um@busy.com", MaxPerSecond: 10}
+	waitFor(limiter, idle)
+	time.Sleep(150 * time.Millisecond)
+	waitFor(limiter, busy)
+
+	limiter.dropIdle(time.Now())
+
+	assert.Len(limiter.recipients, 1)
+	assert.Contains(limiter.recipients, "busy.com")
+	assert.Len(limiter.campaigns, 1)
+	assert.Contains(limiter.campaigns, "cp2")
+}
+
+func Test_Limiter_should_wait_for_the_slowest_bucket_only(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewLimiter(Config{PerSecond: 10, RecipientDomainPerSecond: 10}, "campaigns@emailn.com")
+	message := &campaign.Message{CampaignID: "cp1", To: "um@test.com", MaxPerSecond: 10}
+	waitFor(limiter, message)
+
+	elapsed := waitFor(limiter, message)
+
+	assert.GreaterOrEqual(elapsed, 90*time.Millisecond)
+	assert.Less(elapsed, 150*time.Millisecond)
+}
+
+func Test_Limiter_should_give_the_tokens_back_when_ctx_is_done(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewLimiter(Config{PerSecond: 10, RecipientDomainPerSecond: 1}, "campaigns@emailn.com")
+	message := &campaign.Message{To: "um@test.com"}
+	waitFor(limiter, message)
+	time.Sleep(150 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	err := limiter.Wait(ctx, message)
+
+	assert.ErrorIs(err, context.Canceled)
+	assert.InDelta(1, limiter.global.TokensAt(time.Now()), 0.01)
+}
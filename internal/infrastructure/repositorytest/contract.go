@@ -0,0 +1,463 @@
+// Package repositorytest holds the behavior every storage backend of the
+// repositories must share, run by the tests of each backend.
+package repositorytest
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+	"emailn/internal/domain/idempotency"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// CampaignRepository is the campaign.Repository of a backend with the
+// counters read by the metrics.
+type CampaignRepository interface {
+	campaign.Repository
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+	CountPendingContacts(ctx context.Context) (int64, error)
+}
+
+func newCampaign(emails ...string) *campaign.Campaign {
+	if len(emails) == 0 {
+		emails = []string{"teste1@test.com", "Teste2@test.com"}
+	}
+	created, _ := campaign.NewCampaign("Test Y", "body HI!", emails, "teste@test.com.br")
+	return created
+}
+
+// RunCampaignRepository runs the contract against the empty repositories
+// returned by newRepository.
+func RunCampaignRepository(t *testing.T, newRepository func(t *testing.T) CampaignRepository) {
+	ctx := context.Background()
+
+	t.Run("GetBy loads the campaign with its contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		assert.Nil(repository.Create(ctx, created))
+
+		saved, err := repository.GetBy(ctx, created.ID)
+
+		assert.Nil(err)
+		assert.Equal(created.Name, saved.Name)
+		assert.Equal(created.Content, saved.Content)
+		assert.Equal(created.CreatedBy, saved.CreatedBy)
+		assert.Equal(campaign.Peding, saved.Status)
+		assert.WithinDuration(created.CreatedOn, saved.CreatedOn, time.Second)
+		assert.Len(saved.Contacts, 2)
+		assert.ElementsMatch([]string{"teste1@test.com", "Teste2@test.com"}, []string{saved.Contacts[0].Email, saved.Contacts[1].Email})
+		assert.Equal(created.ID, saved.Contacts[0].CampaignId)
+	})
+
+	t.Run("GetBy returns record not found", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.GetBy(ctx, "missing")
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Create rejects a duplicated id", func(t *testing.T) {
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+
+		err := repository.Create(ctx, created)
+
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("Update saves the campaign and its contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		created.Start()
+		created.Contacts[0].MarkSending(created.SendRunID)
+		created.Contacts[0].MarkSent("<1@test>")
+
+		assert.Nil(repository.Update(ctx, created))
+		saved, _ := repository.GetBy(ctx, created.ID)
+
+		assert.Equal(campaign.Started, saved.Status)
+		assert.Equal(created.SendRunID, saved.SendRunID)
+		assert.NotNil(saved.StartedOn)
+		sent, _ := repository.GetContactBy(ctx, created.Contacts[0].ID)
+		assert.Equal(campaign.ContactSent, sent.Status)
+		assert.Equal("<1@test>", sent.ProviderMessageID)
+		assert.NotNil(sent.SentOn)
+	})
+
+	t.Run("Create and Update handle thousands of contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		emails := make([]string, 5000)
+		for i := range emails {
+			emails[i] = fmt.Sprintf("contact%d@test.com", i)
+		}
+		created := newCampaign(emails...)
+
+		assert.Nil(repository.Create(ctx, created))
+		created.Start()
+		for i := range created.Contacts {
+			created.Contacts[i].MarkSending(created.SendRunID)
+		}
+		assert.Nil(repository.Update(ctx, created))
+		saved, _ := repository.GetBy(ctx, created.ID)
+
+		assert.Len(saved.Contacts, 5000)
+		last, _ := repository.GetContactBy(ctx, created.Contacts[4999].ID)
+		assert.Equal(campaign.ContactSending, last.Status)
+	})
+
+	t.Run("Update only writes the changed contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		loaded, _ := repository.GetBy(ctx, created.ID)
+		loaded.Contacts[0].MarkFailed()
+		// not marked, left as stored
+		loaded.Contacts[1].Email = "changed@test.com"
+
+		assert.Nil(repository.Update(ctx, loaded))
+		failed, _ := repository.GetContactBy(ctx, loaded.Contacts[0].ID)
+		untouched, _ := repository.GetContactBy(ctx, loaded.Contacts[1].ID)
+
+		assert.Equal(campaign.ContactFailed, failed.Status)
+		assert.Equal(created.Contacts[1].Email, untouched.Email)
+		assert.Empty(loaded.ChangedContacts())
+	})
+
+	t.Run("Update bumps the version", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		created.Start()
+
+		assert.Nil(repository.Update(ctx, created))
+		saved, _ := repository.GetBy(ctx, created.ID)
+
+		assert.Equal(2, created.Version)
+		assert.Equal(2, saved.Version)
+	})
+
+	t.Run("Update fails with ErrVersionConflict when the campaign changed", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		first, _ := repository.GetBy(ctx, created.ID)
+		second, _ := repository.GetBy(ctx, created.ID)
+		first.Start()
+		repository.Update(ctx, first)
+		second.Cancel()
+
+		err := repository.Update(ctx, second)
+
+		assert.ErrorIs(err, campaign.ErrVersionConflict)
+		assert.Equal(1, second.Version)
+		saved, _ := repository.GetBy(ctx, created.ID)
+		assert.Equal(campaign.Started, saved.Status)
+	})
+
+	t.Run("Delete fails with ErrVersionConflict when the campaign changed", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		stale, _ := repository.GetBy(ctx, created.ID)
+		created.Start()
+		repository.Update(ctx, created)
+		stale.Delete()
+
+		err := repository.Delete(ctx, stale)
+
+		assert.ErrorIs(err, campaign.ErrVersionConflict)
+		_, err = repository.GetBy(ctx, created.ID)
+		assert.Nil(err)
+	})
+
+	t.Run("GetByStatus returns the campaigns without contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		started := newCampaign()
+		started.Start()
+		repository.Create(ctx, started)
+		repository.Create(ctx, newCampaign())
+
+		campaigns, err := repository.GetByStatus(ctx, campaign.Started)
+
+		assert.Nil(err)
+		assert.Len(campaigns, 1)
+		assert.Equal(started.ID, campaigns[0].ID)
+		assert.Empty(campaigns[0].Contacts)
+	})
+
+	t.Run("GetStats counts the contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign("a@test.com", "b@test.com", "c@test.com", "d@test.com")
+		repository.Create(ctx, created)
+		created.Contacts[0].MarkSent("<1@test>")
+		created.Contacts[1].MarkSent("<2@test>")
+		created.Contacts[1].MarkBounced()
+		created.Contacts[2].MarkFailed()
+		repository.UpdateContacts(ctx, []*campaign.Contact{&created.Contacts[0], &created.Contacts[1], &created.Contacts[2]})
+
+		stats, err := repository.GetStats(ctx, created.ID)
+
+		assert.Nil(err)
+		assert.Equal(created.ID, stats.CampaignID)
+		assert.Equal(int64(4), stats.Total)
+		assert.Equal(int64(2), stats.Sent)
+		assert.Equal(int64(1), stats.Bounced)
+		assert.Equal(int64(1), stats.Failed)
+	})
+
+	t.Run("GetStats returns record not found", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.GetStats(ctx, "missing")
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("GetOwner returns the creator of a deleted campaign too", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		created.Delete()
+		repository.Delete(ctx, created)
+
+		owner, err := repository.GetOwner(ctx, created.ID)
+
+		assert.Nil(err)
+		assert.Equal("teste@test.com.br", owner)
+		_, err = repository.GetOwner(ctx, "missing")
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("UpdateContact saves one contact", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		contact, _ := repository.GetContactBy(ctx, created.Contacts[1].ID)
+		contact.MarkFailed()
+
+		assert.Nil(repository.UpdateContact(ctx, contact))
+		saved, _ := repository.GetBy(ctx, created.ID)
+
+		for _, saved := range saved.Contacts {
+			if saved.ID == contact.ID {
+				assert.Equal(campaign.ContactFailed, saved.Status)
+			} else {
+				assert.Equal(campaign.ContactPending, saved.Status)
+			}
+		}
+	})
+
+	t.Run("GetContactBy returns record not found", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.GetContactBy(ctx, "missing")
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Delete hides the campaign from the reads", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		created.Delete()
+
+		assert.Nil(repository.Delete(ctx, created))
+
+		_, err := repository.GetBy(ctx, created.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		_, err = repository.GetStats(ctx, created.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		deleted, _ := repository.GetByStatus(ctx, campaign.Deleted)
+		assert.Empty(deleted)
+		all, _ := repository.Get(ctx)
+		assert.Empty(all)
+	})
+
+	t.Run("GetDeletedBy loads the deleted campaign", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		_, err := repository.GetDeletedBy(ctx, created.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		created.Delete()
+		repository.Delete(ctx, created)
+
+		deleted, err := repository.GetDeletedBy(ctx, created.ID)
+
+		assert.Nil(err)
+		assert.Equal(campaign.Deleted, deleted.Status)
+		assert.NotNil(deleted.DeletedOn)
+		assert.Len(deleted.Contacts, 2)
+	})
+
+	t.Run("Update restores a deleted campaign", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		created.Delete()
+		repository.Delete(ctx, created)
+		deleted, _ := repository.GetDeletedBy(ctx, created.ID)
+		deleted.Restore()
+
+		assert.Nil(repository.Update(ctx, deleted))
+		restored, err := repository.GetBy(ctx, created.ID)
+
+		assert.Nil(err)
+		assert.Equal(campaign.Peding, restored.Status)
+		assert.Nil(restored.DeletedOn)
+		assert.Len(restored.Contacts, 2)
+	})
+
+	t.Run("Purge removes the campaigns deleted before the cutoff", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		old, recent, kept := newCampaign(), newCampaign(), newCampaign()
+		for _, created := range []*campaign.Campaign{old, recent, kept} {
+			repository.Create(ctx, created)
+		}
+		old.Delete()
+		longAgo := time.Now().Add(-48 * time.Hour)
+		old.DeletedOn = &longAgo
+		repository.Delete(ctx, old)
+		recent.Delete()
+		repository.Delete(ctx, recent)
+
+		purged, err := repository.Purge(ctx, time.Now().Add(-24*time.Hour))
+
+		assert.Nil(err)
+		assert.Equal(int64(1), purged)
+		_, err = repository.GetDeletedBy(ctx, old.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		_, err = repository.GetContactBy(ctx, old.Contacts[0].ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		_, err = repository.GetDeletedBy(ctx, recent.ID)
+		assert.Nil(err)
+		_, err = repository.GetBy(ctx, kept.ID)
+		assert.Nil(err)
+	})
+
+	t.Run("GetSuppressedEmails ignores the case of the contacts", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		created := newCampaign()
+		repository.Create(ctx, created)
+		assert.Nil(repository.CreateSuppression(ctx, campaign.NewSuppression("Teste2@test.com", "5.1.1")))
+		assert.Nil(repository.CreateSuppression(ctx, campaign.NewSuppression("teste2@test.com", "5.1.1 again")))
+
+		emails, err := repository.GetSuppressedEmails(ctx, created.ID)
+
+		assert.Nil(err)
+		assert.Equal([]string{"Teste2@test.com"}, emails)
+	})
+
+	t.Run("counts campaigns by status and pending sends", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		started := newCampaign()
+		started.Start()
+		repository.Create(ctx, started)
+		repository.Create(ctx, newCampaign())
+		started.Contacts[0].MarkSent("<1@test>")
+		repository.UpdateContact(ctx, &started.Contacts[0])
+
+		statuses, err := repository.CountByStatus(ctx)
+		assert.Nil(err)
+		pending, err := repository.CountPendingContacts(ctx)
+		assert.Nil(err)
+
+		assert.Equal(map[string]int64{campaign.Started: 1, campaign.Peding: 1}, statuses)
+		assert.Equal(int64(1), pending)
+	})
+}
+
+// RunIdempotencyRepository runs the contract against the empty repositories
+// returned by newRepository.
+func RunIdempotencyRepository(t *testing.T, newRepository func(t *testing.T) idempotency.Repository) {
+	ctx := context.Background()
+	newRecord := func() *idempotency.Record {
+		return &idempotency.Record{Key: "key1", CreatedBy: "teste@test.com.br", RequestHash: "abc", CreatedOn: time.Now()}
+	}
+
+	t.Run("Create rejects a key used by the same user", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		assert.Nil(repository.Create(ctx, newRecord()))
+
+		err := repository.Create(ctx, newRecord())
+		other := newRecord()
+		other.CreatedBy = "other@test.com.br"
+
+		assert.ErrorIs(err, gorm.ErrDuplicatedKey)
+		assert.Nil(repository.Create(ctx, other))
+	})
+
+	t.Run("Update saves the response", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		record := newRecord()
+		repository.Create(ctx, record)
+		record.StatusCode = 201
+		record.Response = `{"id":"1"}`
+
+		assert.Nil(repository.Update(ctx, record))
+		saved, err := repository.GetBy(ctx, record.Key, record.CreatedBy)
+
+		assert.Nil(err)
+		assert.Equal(201, saved.StatusCode)
+		assert.Equal(`{"id":"1"}`, saved.Response)
+		assert.Equal("abc", saved.RequestHash)
+	})
+
+	t.Run("Delete forgets the key", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		record := newRecord()
+		repository.Create(ctx, record)
+
+		assert.Nil(repository.Delete(ctx, record))
+		_, err := repository.GetBy(ctx, record.Key, record.CreatedBy)
+
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Purge deletes the records created before the time", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		old := newRecord()
+		old.CreatedOn = time.Now().Add(-2 * time.Hour)
+		repository.Create(ctx, old)
+		recent := newRecord()
+		recent.Key = "key2"
+		repository.Create(ctx, recent)
+
+		purged, err := repository.Purge(ctx, time.Now().Add(-time.Hour))
+
+		assert.Nil(err)
+		assert.Equal(int64(1), purged)
+		_, err = repository.GetBy(ctx, old.Key, old.CreatedBy)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		_, err = repository.GetBy(ctx, recent.Key, recent.CreatedBy)
+		assert.Nil(err)
+	})
+}
@@ -0,0 +1,150 @@
+package repositorytest
+
+import (
+	"context"
+	"emailn/internal/domain/job"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// RunJobRepository runs the contract against the empty repositories returned
+// by newRepository.
+func RunJobRepository(t *testing.T, newRepository func(t *testing.T) job.Repository) {
+	ctx := context.Background()
+	kinds := []string{"test.send"}
+	newJob := func(key string, runAt time.Time) *job.Job {
+		created, _ := job.New("test.send", key, map[string]string{"campaign_id": "1"}, runAt)
+		created.MaxAttempts = 3
+		return created
+	}
+
+	t.Run("Claim leases the oldest due job", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		older, newer, later := newJob("", now.Add(-2*time.Minute)), newJob("", now.Add(-time.Minute)), newJob("", now.Add(time.Minute))
+		for _, created := range []*job.Job{newer, later, older} {
+			assert.Nil(repository.Create(ctx, created))
+		}
+
+		claimed, err := repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Minute))
+
+		assert.Nil(err)
+		assert.Equal(older.ID, claimed.ID)
+		assert.Equal(job.Running, claimed.Status)
+		assert.Equal(1, claimed.Attempts)
+		assert.Equal("worker1", claimed.LockedBy)
+		var payload map[string]string
+		assert.Nil(claimed.Decode(&payload))
+		assert.Equal("1", payload["campaign_id"])
+		saved, _ := repository.GetBy(ctx, older.ID)
+		assert.Equal(job.Running, saved.Status)
+		assert.Equal("worker1", saved.LockedBy)
+	})
+
+	t.Run("Claim skips leased, future and other kinds of jobs", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		repository.Create(ctx, newJob("", now.Add(-time.Minute)))
+		repository.Create(ctx, newJob("", now.Add(time.Hour)))
+		other, _ := job.New("other", "", nil, now.Add(-time.Minute))
+		repository.Create(ctx, other)
+		repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Minute))
+
+		_, err := repository.Claim(ctx, kinds, "worker2", now, now.Add(time.Minute))
+
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Claim takes over a job whose lease expired", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		created := newJob("", now.Add(-time.Minute))
+		repository.Create(ctx, created)
+		stale, _ := repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Second))
+
+		claimed, err := repository.Claim(ctx, kinds, "worker2", now.Add(2*time.Second), now.Add(time.Minute))
+
+		assert.Nil(err)
+		assert.Equal(created.ID, claimed.ID)
+		assert.Equal(2, claimed.Attempts)
+		assert.ErrorIs(repository.Extend(ctx, stale, now.Add(time.Hour)), job.ErrLeaseLost)
+		stale.Done()
+		assert.ErrorIs(repository.Finish(ctx, stale), job.ErrLeaseLost)
+	})
+
+	t.Run("Create rejects a second active job with the same key", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		assert.Nil(repository.Create(ctx, newJob("campaign:1", now)))
+
+		err := repository.Create(ctx, newJob("campaign:1", now))
+
+		assert.ErrorIs(err, gorm.ErrDuplicatedKey)
+		assert.Nil(repository.Create(ctx, newJob("campaign:2", now)))
+		assert.Nil(repository.Create(ctx, newJob("", now)))
+		assert.Nil(repository.Create(ctx, newJob("", now)))
+	})
+
+	t.Run("Finish removes a done job and frees its key", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		created := newJob("campaign:1", now.Add(-time.Second))
+		repository.Create(ctx, created)
+		claimed, _ := repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Minute))
+		claimed.Done()
+
+		assert.Nil(repository.Finish(ctx, claimed))
+
+		_, err := repository.GetBy(ctx, created.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		assert.Nil(repository.Create(ctx, newJob("campaign:1", now)))
+	})
+
+	t.Run("Finish queues a retried job again", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		repository.Create(ctx, newJob("", now.Add(-time.Second)))
+		claimed, _ := repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Minute))
+		claimed.Retry(now.Add(time.Minute), errors.New("relay down"))
+
+		assert.Nil(repository.Finish(ctx, claimed))
+
+		saved, _ := repository.GetBy(ctx, claimed.ID)
+		assert.Equal(job.Queued, saved.Status)
+		assert.Equal("relay down", saved.LastError)
+		assert.Nil(saved.LockedUntil)
+		_, err := repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Minute))
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		again, err := repository.Claim(ctx, kinds, "worker2", now.Add(2*time.Minute), now.Add(3*time.Minute))
+		assert.Nil(err)
+		assert.Equal(2, again.Attempts)
+	})
+
+	t.Run("Finish keeps a dead job out of the queue", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		now := time.Now().UTC()
+		repository.Create(ctx, newJob("campaign:1", now.Add(-time.Second)))
+		claimed, _ := repository.Claim(ctx, kinds, "worker1", now, now.Add(time.Minute))
+		claimed.Kill(errors.New("relay down"))
+
+		assert.Nil(repository.Finish(ctx, claimed))
+
+		saved, _ := repository.GetBy(ctx, claimed.ID)
+		assert.Equal(job.Dead, saved.Status)
+		assert.NotNil(saved.FinishedOn)
+		_, err := repository.Claim(ctx, kinds, "worker1", now.Add(time.Hour), now.Add(2*time.Hour))
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		assert.Nil(repository.Create(ctx, newJob("campaign:1", now)))
+	})
+}
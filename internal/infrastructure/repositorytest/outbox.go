@@ -0,0 +1,82 @@
+package repositorytest
+
+import (
+	"context"
+	"emailn/internal/domain/outbox"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// RunOutboxRepository runs the contract against the empty repositories
+// returned by newRepository.
+func RunOutboxRepository(t *testing.T, newRepository func(t *testing.T) outbox.Repository) {
+	ctx := context.Background()
+
+	t.Run("Next returns the oldest message", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		older, _ := outbox.New("campaign.send", "campaign.send:1", map[string]string{"campaign_id": "1"})
+		newer, _ := outbox.New("campaign.send", "campaign.send:2", map[string]string{"campaign_id": "2"})
+		newer.CreatedOn = older.CreatedOn.Add(time.Second)
+		assert.Nil(repository.Create(ctx, older))
+		assert.Nil(repository.Create(ctx, newer))
+
+		next, err := repository.Next(ctx, time.Now())
+
+		assert.Nil(err)
+		assert.Equal(older.ID, next.ID)
+		assert.Equal("campaign.send", next.Topic)
+		assert.Equal("campaign.send:1", next.Key)
+		assert.JSONEq(`{"campaign_id":"1"}`, next.Payload)
+	})
+
+	t.Run("Next returns ErrRecordNotFound when the outbox is empty", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Next(ctx, time.Now())
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Next skips the messages backing off and the dead ones", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		backingOff, _ := outbox.New("campaign.send", "campaign.send:1", nil)
+		dead, _ := outbox.New("campaign.send", "campaign.send:2", nil)
+		due, _ := outbox.New("campaign.send", "campaign.send:3", nil)
+		dead.CreatedOn = backingOff.CreatedOn.Add(time.Second)
+		due.CreatedOn = backingOff.CreatedOn.Add(2 * time.Second)
+		repository.Create(ctx, backingOff)
+		repository.Create(ctx, dead)
+		repository.Create(ctx, due)
+		backingOff.Retry(time.Now().Add(time.Minute), errors.New("queue unavailable"))
+		dead.Kill(errors.New("queue unavailable"))
+		assert.Nil(repository.Update(ctx, backingOff))
+		assert.Nil(repository.Update(ctx, dead))
+
+		next, err := repository.Next(ctx, time.Now())
+		later, _ := repository.Next(ctx, time.Now().Add(2*time.Minute))
+
+		assert.Nil(err)
+		assert.Equal(due.ID, next.ID)
+		assert.Equal(backingOff.ID, later.ID)
+		assert.Equal(1, later.Attempts)
+		assert.Equal("queue unavailable", later.LastError)
+	})
+
+	t.Run("Delete removes the message", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		message, _ := outbox.New("campaign.send", "", nil)
+		repository.Create(ctx, message)
+
+		assert.Nil(repository.Delete(ctx, message))
+
+		_, err := repository.Next(ctx, time.Now())
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	})
+}
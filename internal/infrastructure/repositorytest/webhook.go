@@ -0,0 +1,154 @@
+package repositorytest
+
+import (
+	"context"
+	"emailn/internal/domain/webhook"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// RunWebhookRepository runs the contract against the empty repositories
+// returned by newRepository.
+func RunWebhookRepository(t *testing.T, newRepository func(t *testing.T) webhook.Repository) {
+	ctx := context.Background()
+	newSubscription := func(createdBy string) *webhook.Subscription {
+		subscription, _ := webhook.NewSubscription("https://crm.example.com/hooks", []string{webhook.CampaignStarted}, "", createdBy)
+		return subscription
+	}
+	newDelivery := func(subscription *webhook.Subscription, eventType string) *webhook.Delivery {
+		event := &webhook.Event{ID: xid.New().String(), Type: eventType, Data: map[string]string{"campaign_id": "1"}}
+		delivery, _ := webhook.NewDelivery(subscription, event)
+		return delivery
+	}
+
+	t.Run("GetSubscription returns the saved subscription", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		subscription := newSubscription("owner@e.com")
+		assert.Nil(repository.CreateSubscription(ctx, subscription))
+
+		saved, err := repository.GetSubscription(ctx, subscription.ID)
+
+		assert.Nil(err)
+		assert.Equal(subscription.URL, saved.URL)
+		assert.Equal(subscription.Events, saved.Events)
+		assert.Equal(subscription.Secret, saved.Secret)
+		assert.Equal("owner@e.com", saved.CreatedBy)
+	})
+
+	t.Run("GetSubscription returns ErrRecordNotFound for an unknown id", func(t *testing.T) {
+		_, err := newRepository(t).GetSubscription(ctx, "unknown")
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("UpdateSubscription saves the changes", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		subscription := newSubscription("owner@e.com")
+		repository.CreateSubscription(ctx, subscription)
+		subscription.Change("https://crm.example.com/v2", []string{webhook.CampaignFinished, webhook.CampaignFailed}, "")
+
+		assert.Nil(repository.UpdateSubscription(ctx, subscription))
+
+		saved, _ := repository.GetSubscription(ctx, subscription.ID)
+		assert.Equal("https://crm.example.com/v2", saved.URL)
+		assert.Equal([]string{webhook.CampaignFailed, webhook.CampaignFinished}, saved.Events)
+	})
+
+	t.Run("GetSubscriptions filters by creator", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		mine := newSubscription("owner@e.com")
+		theirs := newSubscription("other@e.com")
+		theirs.CreatedOn = mine.CreatedOn.Add(time.Second)
+		repository.CreateSubscription(ctx, mine)
+		repository.CreateSubscription(ctx, theirs)
+
+		owned, err := repository.GetSubscriptions(ctx, "owner@e.com")
+		all, _ := repository.GetSubscriptions(ctx, "")
+
+		assert.Nil(err)
+		assert.Len(owned, 1)
+		assert.Equal(mine.ID, owned[0].ID)
+		assert.Len(all, 2)
+	})
+
+	t.Run("CreateDelivery returns ErrDuplicatedKey for a delivered event", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		subscription := newSubscription("owner@e.com")
+		repository.CreateSubscription(ctx, subscription)
+		delivery := newDelivery(subscription, webhook.CampaignStarted)
+		assert.Nil(repository.CreateDelivery(ctx, delivery))
+
+		err := repository.CreateDelivery(ctx, delivery)
+
+		assert.ErrorIs(err, gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("UpdateDelivery saves the attempt", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		subscription := newSubscription("owner@e.com")
+		repository.CreateSubscription(ctx, subscription)
+		delivery := newDelivery(subscription, webhook.CampaignStarted)
+		repository.CreateDelivery(ctx, delivery)
+		delivery.Attempted(200, nil, false)
+
+		assert.Nil(repository.UpdateDelivery(ctx, delivery))
+
+		saved, err := repository.GetDelivery(ctx, delivery.ID)
+		assert.Nil(err)
+		assert.Equal(webhook.DeliverySucceeded, saved.Status)
+		assert.Equal(1, saved.Attempts)
+		assert.Equal(200, saved.ResponseStatus)
+		assert.NotNil(saved.DeliveredOn)
+		assert.JSONEq(delivery.Payload, saved.Payload)
+	})
+
+	t.Run("GetDeliveries returns the last deliveries newest first", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		subscription := newSubscription("owner@e.com")
+		other := newSubscription("owner@e.com")
+		repository.CreateSubscription(ctx, subscription)
+		repository.CreateSubscription(ctx, other)
+		older := newDelivery(subscription, webhook.CampaignStarted)
+		newer := newDelivery(subscription, webhook.CampaignFinished)
+		newer.CreatedOn = older.CreatedOn.Add(time.Second)
+		newest := newDelivery(subscription, webhook.CampaignFailed)
+		newest.CreatedOn = older.CreatedOn.Add(2 * time.Second)
+		repository.CreateDelivery(ctx, older)
+		repository.CreateDelivery(ctx, newer)
+		repository.CreateDelivery(ctx, newest)
+		repository.CreateDelivery(ctx, newDelivery(other, webhook.CampaignStarted))
+
+		deliveries, err := repository.GetDeliveries(ctx, subscription.ID, 2)
+
+		assert.Nil(err)
+		assert.Len(deliveries, 2)
+		assert.Equal(newest.ID, deliveries[0].ID)
+		assert.Equal(newer.ID, deliveries[1].ID)
+	})
+
+	t.Run("DeleteSubscription removes its deliveries", func(t *testing.T) {
+		assert := assert.New(t)
+		repository := newRepository(t)
+		subscription := newSubscription("owner@e.com")
+		repository.CreateSubscription(ctx, subscription)
+		delivery := newDelivery(subscription, webhook.CampaignStarted)
+		repository.CreateDelivery(ctx, delivery)
+
+		assert.Nil(repository.DeleteSubscription(ctx, subscription))
+
+		_, err := repository.GetSubscription(ctx, subscription.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+		_, err = repository.GetDelivery(ctx, delivery.ID)
+		assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	})
+}
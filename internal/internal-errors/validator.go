@@ -2,29 +2,102 @@ package internalerrors
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// FieldError is one field that failed validation. Field is its JSON path
+// from the validated struct, like contacts[3].email, Rule the tag it broke
+// and Param the parameter of the rule (the 5 of min=5).
+type FieldError struct {
+	Field   string
+	Rule    string
+	Param   string
+	Message string
+}
+
+// ValidationError lists every field of a struct that failed validation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validate caches the struct metadata between calls, building it is the
+// expensive part of validating.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return strings.ToLower(field.Name)
+		}
+		return name
+	})
+	return v
+}
+
 func ValidateStruct(obj interface{}) error {
-	validate := validator.New()
 	err := validate.Struct(obj)
 	if err == nil {
 		return nil
 	}
-	validationErrors := err.(validator.ValidationErrors)
-	validationError := validationErrors[0]
-	field := strings.ToLower(validationError.StructField())
-	switch validationError.Tag() {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, validationError := range validationErrors {
+		field := path(validationError.Namespace())
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    validationError.Tag(),
+			Param:   validationError.Param(),
+			Message: message(field, validationError.Tag(), validationError.Param()),
+		})
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// path drops the name of the validated struct from the namespace,
+// Campaign.contacts[3].email becomes contacts[3].email.
+func path(namespace string) string {
+	_, field, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return field
+}
+
+func message(field string, rule string, param string) string {
+	switch rule {
 	case "required":
-		return errors.New(field + " is required")
+		return field + " is required"
 	case "max":
-		return errors.New(field + " is required with max " + validationError.Param())
+		return field + " is required with max " + param
 	case "min":
-		return errors.New(field + " is required with min " + validationError.Param())
+		return field + " is required with min " + param
 	case "email":
-		return errors.New(field + " is invalid")
+		return field + " is invalid"
+	case "startswith":
+		return field + " must start with " + param
+	}
+	if param != "" {
+		return field + " is invalid (" + rule + "=" + param + ")"
 	}
-	return nil
+	return field + " is invalid (" + rule + ")"
 }
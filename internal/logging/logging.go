@@ -0,0 +1,70 @@
+// Package logging builds the slog logger of the application and carries a
+// request scoped logger in the context, so every line of a request has its
+// request id, the authenticated email and the campaign it works on.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// New returns a logger writing to w at level (debug, info, warn, error) in
+// format (json or text).
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	options := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+type contextKey struct{}
+
+// entry is shared by everything handling one request: attributes added deep
+// in the chain, like the email by the authentication, also reach the access
+// log written by the outermost middleware.
+type entry struct {
+	mutex  sync.Mutex
+	logger *slog.Logger
+}
+
+// NewContext returns a context carrying logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &entry{logger: logger})
+}
+
+// FromContext returns the logger of the request, or fallback when ctx does
+// not carry one. A nil fallback means slog.Default().
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if e, ok := ctx.Value(contextKey{}).(*entry); ok {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		return e.logger
+	}
+	if fallback == nil {
+		return slog.Default()
+	}
+	return fallback
+}
+
+// With adds attributes to every line logged for the request from now on.
+func With(ctx context.Context, args ...any) {
+	if e, ok := ctx.Value(contextKey{}).(*entry); ok {
+		e.mutex.Lock()
+		e.logger = e.logger.With(args...)
+		e.mutex.Unlock()
+	}
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New_should_reject_unknown_level_and_format(t *testing.T) {
+	assert := assert.New(t)
+
+	_, errLevel := New(&bytes.Buffer{}, "verbose", "json")
+	_, errFormat := New(&bytes.Buffer{}, "info", "xml")
+
+	assert.EqualError(errLevel, `invalid log level "verbose"`)
+	assert.EqualError(errFormat, `invalid log format "xml"`)
+}
+
+func Test_Middleware_should_log_request_id_and_attributes_added_by_handlers(t *testing.T) {
+	assert := assert.New(t)
+	var out bytes.Buffer
+	logger, _ := New(&out, "info", "json")
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(Middleware(logger))
+	r.Get("/campaigns/{id}", func(w http.ResponseWriter, r *http.Request) {
+		With(r.Context(), "email", "teste@test.com", "campaign_id", chi.URLParam(r, "id"))
+		FromContext(r.Context(), nil).Info("handling")
+	})
+	req, _ := http.NewRequest("GET", "/campaigns/abc", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(lines, 2)
+	for _, line := range lines {
+		var record map[string]interface{}
+		json.Unmarshal(line, &record)
+		assert.Equal("req-1", record["request_id"])
+		assert.Equal("teste@test.com", record["email"])
+		assert.Equal("abc", record["campaign_id"])
+	}
+	var access map[string]interface{}
+	json.Unmarshal(lines[1], &access)
+	assert.Equal("/campaigns/{id}", access["route"])
+	assert.Equal(float64(200), access["status"])
+}
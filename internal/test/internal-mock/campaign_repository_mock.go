@@ -1,7 +1,9 @@
 package internalmock
 
 import (
+	"context"
 	"emailn/internal/domain/campaign"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -10,30 +12,95 @@ type CampaignRepositoryMock struct {
 	mock.Mock
 }
 
-func (r *CampaignRepositoryMock) Create(campaign *campaign.Campaign) error {
+func (r *CampaignRepositoryMock) Create(ctx context.Context, campaign *campaign.Campaign) error {
 	args := r.Called(campaign)
 	return args.Error(0)
 }
 
-func (r *CampaignRepositoryMock) Update(campaign *campaign.Campaign) error {
+func (r *CampaignRepositoryMock) Update(ctx context.Context, campaign *campaign.Campaign) error {
 	args := r.Called(campaign)
 	return args.Error(0)
 }
 
-func (r *CampaignRepositoryMock) Delete(campaign *campaign.Campaign) error {
+func (r *CampaignRepositoryMock) Delete(ctx context.Context, campaign *campaign.Campaign) error {
 	args := r.Called(campaign)
 	return args.Error(0)
 }
 
-func (r *CampaignRepositoryMock) Get() ([]campaign.Campaign, error) {
+func (r *CampaignRepositoryMock) GetDeletedBy(ctx context.Context, id string) (*campaign.Campaign, error) {
+	args := r.Called(id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*campaign.Campaign), nil
+}
+
+func (r *CampaignRepositoryMock) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := r.Called(deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *CampaignRepositoryMock) Get(ctx context.Context) ([]campaign.Campaign, error) {
 	// args := r.Called(campaign)
 	return nil, nil
 }
 
-func (r *CampaignRepositoryMock) GetBy(id string) (*campaign.Campaign, error) {
+func (r *CampaignRepositoryMock) GetBy(ctx context.Context, id string) (*campaign.Campaign, error) {
 	args := r.Called(id)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*campaign.Campaign), nil
 }
+
+func (r *CampaignRepositoryMock) GetStats(ctx context.Context, id string) (*campaign.Stats, error) {
+	args := r.Called(id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*campaign.Stats), nil
+}
+
+func (r *CampaignRepositoryMock) GetOwner(ctx context.Context, id string) (string, error) {
+	args := r.Called(id)
+	return args.String(0), args.Error(1)
+}
+
+func (r *CampaignRepositoryMock) GetContactBy(ctx context.Context, id string) (*campaign.Contact, error) {
+	args := r.Called(id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*campaign.Contact), nil
+}
+
+func (r *CampaignRepositoryMock) UpdateContact(ctx context.Context, contact *campaign.Contact) error {
+	args := r.Called(contact)
+	return args.Error(0)
+}
+
+func (r *CampaignRepositoryMock) UpdateContacts(ctx context.Context, contacts []*campaign.Contact) error {
+	args := r.Called(contacts)
+	return args.Error(0)
+}
+
+func (r *CampaignRepositoryMock) CreateSuppression(ctx context.Context, suppression *campaign.Suppression) error {
+	args := r.Called(suppression)
+	return args.Error(0)
+}
+
+func (r *CampaignRepositoryMock) GetSuppressedEmails(ctx context.Context, campaignID string) ([]string, error) {
+	args := r.Called(campaignID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), nil
+}
+
+func (r *CampaignRepositoryMock) GetByStatus(ctx context.Context, status string) ([]campaign.Campaign, error) {
+	args := r.Called(status)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]campaign.Campaign), nil
+}
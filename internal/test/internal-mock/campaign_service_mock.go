@@ -1,7 +1,10 @@
 package internalmock
 
 import (
+	"context"
 	"emailn/internal/contract"
+	"emailn/internal/domain/campaign"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -10,7 +13,7 @@ type CampaignServiceMock struct {
 	mock.Mock
 }
 
-func (r *CampaignServiceMock) Create(newCampaign contract.NewCampaign) (string, error) {
+func (r *CampaignServiceMock) Create(ctx context.Context, newCampaign contract.NewCampaign) (string, error) {
 	args := r.Called(newCampaign)
 	return args.String(0), args.Error(1)
 }
@@ -20,7 +23,7 @@ func (r *CampaignServiceMock) Update(newCampaign contract.NewCampaign) (string,
 	return args.String(0), args.Error(1)
 }
 
-func (r *CampaignServiceMock) GetBy(id string) (*contract.CampaignResponse, error) {
+func (r *CampaignServiceMock) GetBy(ctx context.Context, id string) (*contract.CampaignResponse, error) {
 	args := r.Called(id)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
@@ -28,11 +31,48 @@ func (r *CampaignServiceMock) GetBy(id string) (*contract.CampaignResponse, erro
 	return args.Get(0).(*contract.CampaignResponse), nil
 }
 
-func (r *CampaignServiceMock) Delete(id string) error {
+func (r *CampaignServiceMock) GetStats(ctx context.Context, id string) (*contract.CampaignStatsResponse, error) {
+	args := r.Called(id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contract.CampaignStatsResponse), nil
+}
+
+func (r *CampaignServiceMock) Delete(ctx context.Context, id string) error {
+	args := r.Called(id)
+	return args.Error(0)
+}
+
+func (r *CampaignServiceMock) Restore(ctx context.Context, id string) error {
+	args := r.Called(id)
+	return args.Error(0)
+}
+
+func (r *CampaignServiceMock) Start(ctx context.Context, id string) error {
 	args := r.Called(id)
 	return args.Error(0)
 }
-func (r *CampaignServiceMock) Start(id string) error {
+
+func (r *CampaignServiceMock) Resume(ctx context.Context, id string) error {
 	args := r.Called(id)
 	return args.Error(0)
 }
+
+func (r *CampaignServiceMock) Schedule(ctx context.Context, id string, at time.Time) error {
+	args := r.Called(id, at)
+	return args.Error(0)
+}
+
+func (r *CampaignServiceMock) ProcessBounce(ctx context.Context, bounce campaign.Bounce) error {
+	args := r.Called(bounce)
+	return args.Error(0)
+}
+
+func (r *CampaignServiceMock) Follow(ctx context.Context, id string, lastEventID string) (*campaign.ProgressStream, error) {
+	args := r.Called(id, lastEventID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*campaign.ProgressStream), nil
+}
@@ -0,0 +1,63 @@
+package internalmock
+
+import (
+	"context"
+	"emailn/internal/domain/idempotency"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type IdempotencyRepositoryMock struct {
+	mock.Mock
+}
+
+func (r *IdempotencyRepositoryMock) GetBy(ctx context.Context, key string, createdBy string) (*idempotency.Record, error) {
+	args := r.Called(key, createdBy)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*idempotency.Record), nil
+}
+
+func (r *IdempotencyRepositoryMock) Create(ctx context.Context, record *idempotency.Record) error {
+	args := r.Called(record)
+	return args.Error(0)
+}
+
+func (r *IdempotencyRepositoryMock) Update(ctx context.Context, record *idempotency.Record) error {
+	args := r.Called(record)
+	return args.Error(0)
+}
+
+func (r *IdempotencyRepositoryMock) Delete(ctx context.Context, record *idempotency.Record) error {
+	args := r.Called(record)
+	return args.Error(0)
+}
+
+func (r *IdempotencyRepositoryMock) Purge(ctx context.Context, createdBefore time.Time) (int64, error) {
+	args := r.Called(createdBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type IdempotencyServiceMock struct {
+	mock.Mock
+}
+
+func (s *IdempotencyServiceMock) Begin(ctx context.Context, key string, createdBy string, requestHash string) (*idempotency.Record, error) {
+	args := s.Called(key, createdBy, requestHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*idempotency.Record), nil
+}
+
+func (s *IdempotencyServiceMock) Complete(ctx context.Context, record *idempotency.Record, statusCode int, response string) error {
+	args := s.Called(record, statusCode, response)
+	return args.Error(0)
+}
+
+func (s *IdempotencyServiceMock) Abort(ctx context.Context, record *idempotency.Record) error {
+	args := s.Called(record)
+	return args.Error(0)
+}
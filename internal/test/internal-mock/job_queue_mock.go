@@ -0,0 +1,17 @@
+package internalmock
+
+import (
+	"context"
+	"emailn/internal/domain/job"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type JobQueueMock struct {
+	mock.Mock
+}
+
+func (q *JobQueueMock) Enqueue(ctx context.Context, job *job.Job) error {
+	args := q.Called(job)
+	return args.Error(0)
+}
@@ -0,0 +1,48 @@
+package internalmock
+
+import (
+	"context"
+	"emailn/internal/domain/campaign"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MailerMock struct {
+	mock.Mock
+}
+
+func (m *MailerMock) Send(ctx context.Context, message *campaign.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+type BatchMailerMock struct {
+	MailerMock
+	Size int
+}
+
+func (m *BatchMailerMock) BatchSize() int {
+	return m.Size
+}
+
+func (m *BatchMailerMock) SendBatch(ctx context.Context, messages []*campaign.Message) error {
+	args := m.Called(messages)
+	return args.Error(0)
+}
+
+type ThrottleMock struct {
+	mock.Mock
+}
+
+func (m *ThrottleMock) Wait(ctx context.Context, message *campaign.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+type MetricsMock struct {
+	mock.Mock
+}
+
+func (m *MetricsMock) CountMessages(result string, count int) {
+	m.Called(result, count)
+}
@@ -0,0 +1,46 @@
+package internalmock
+
+import (
+	"context"
+	"emailn/internal/domain/outbox"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type OutboxRepositoryMock struct {
+	mock.Mock
+}
+
+func (o *OutboxRepositoryMock) Create(ctx context.Context, message *outbox.Message) error {
+	args := o.Called(message)
+	return args.Error(0)
+}
+
+func (o *OutboxRepositoryMock) Next(ctx context.Context, now time.Time) (*outbox.Message, error) {
+	args := o.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*outbox.Message), args.Error(1)
+}
+
+func (o *OutboxRepositoryMock) Update(ctx context.Context, message *outbox.Message) error {
+	args := o.Called(message)
+	return args.Error(0)
+}
+
+func (o *OutboxRepositoryMock) Delete(ctx context.Context, message *outbox.Message) error {
+	args := o.Called(message)
+	return args.Error(0)
+}
+
+// UnitOfWorkMock runs the functions as they are and counts the transactions.
+type UnitOfWorkMock struct {
+	Transactions int
+}
+
+func (u *UnitOfWorkMock) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	u.Transactions++
+	return fn(ctx)
+}
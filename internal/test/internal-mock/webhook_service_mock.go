@@ -0,0 +1,62 @@
+package internalmock
+
+import (
+	"context"
+	"emailn/internal/contract"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookServiceMock struct {
+	mock.Mock
+}
+
+func (r *WebhookServiceMock) Create(ctx context.Context, newWebhook contract.NewWebhook) (*contract.WebhookResponse, error) {
+	args := r.Called(newWebhook)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contract.WebhookResponse), nil
+}
+
+func (r *WebhookServiceMock) Get(ctx context.Context, createdBy string) ([]contract.WebhookResponse, error) {
+	args := r.Called(createdBy)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]contract.WebhookResponse), nil
+}
+
+func (r *WebhookServiceMock) GetBy(ctx context.Context, id string, createdBy string) (*contract.WebhookResponse, error) {
+	args := r.Called(id, createdBy)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contract.WebhookResponse), nil
+}
+
+func (r *WebhookServiceMock) Update(ctx context.Context, id string, changed contract.NewWebhook) (*contract.WebhookResponse, error) {
+	args := r.Called(id, changed)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contract.WebhookResponse), nil
+}
+
+func (r *WebhookServiceMock) Delete(ctx context.Context, id string, createdBy string) error {
+	args := r.Called(id, createdBy)
+	return args.Error(0)
+}
+
+func (r *WebhookServiceMock) GetDeliveries(ctx context.Context, id string, createdBy string) ([]contract.WebhookDeliveryResponse, error) {
+	args := r.Called(id, createdBy)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]contract.WebhookDeliveryResponse), nil
+}
+
+func (r *WebhookServiceMock) Replay(ctx context.Context, id string, deliveryID string, createdBy string) error {
+	args := r.Called(id, deliveryID, createdBy)
+	return args.Error(0)
+}
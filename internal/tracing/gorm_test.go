@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"gorm.io/gorm"
+)
+
+func Test_GormPlugin_should_name_the_database_of_the_dialector(t *testing.T) {
+	assert := assert.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Use(GormPlugin{})
+	ctx, parent := otel.Tracer(tracerName).Start(context.Background(), "test")
+
+	var one int
+	db.WithContext(ctx).Raw("SELECT 1").Scan(&one)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(spans, 2)
+	assert.Equal("gorm.row", spans[0].Name())
+	assert.Contains(spans[0].Attributes(), semconv.DBSystemSqlite)
+}